@@ -0,0 +1,652 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+// Gap is a silence between two consecutive utterances.
+// Start, end, and duration are in seconds.
+type Gap struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+}
+
+// detectGaps returns the silences between consecutive utterances longer than min seconds.
+// Utterances must be ordered by start time. Overlapping utterances never produce a gap,
+// since each gap is measured from the latest end seen so far.
+func detectGaps(utterances []CleanUtterance, min float64) []Gap {
+	gaps := []Gap{}
+	if len(utterances) == 0 {
+		return gaps
+	}
+
+	lastEnd := utterances[0].End
+	for _, u := range utterances[1:] {
+		if d := u.Start - lastEnd; d > min {
+			gaps = append(gaps, Gap{Start: lastEnd, End: u.Start, Duration: d})
+		}
+		if u.End > lastEnd {
+			lastEnd = u.End
+		}
+	}
+	return gaps
+}
+
+// handleGetGaps returns the silences in a transcription longer than ?min seconds (default 2).
+// If the transcription is not found, it returns a 404 error.
+func handleGetGaps(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	min := 2.0
+	if v := r.URL.Query().Get("min"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "min must be a non-negative number of seconds", http.StatusBadRequest)
+			return
+		}
+		min = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detectGaps(data, min))
+}
+
+// Meeting is one of the meetings found in a long recording by splitMeetings.
+// Speakers lists the speaker labels of the meeting in order of first appearance.
+type Meeting struct {
+	Start      float64          `json:"start"`
+	End        float64          `json:"end"`
+	Speakers   []string         `json:"speakers"`
+	Utterances []CleanUtterance `json:"utterances"`
+}
+
+// splitAtGaps splits utterances into runs separated by the silences longer than
+// min seconds that detectGaps finds. Utterances must be ordered by start time.
+// Each run holds at least one utterance; no utterances give no runs.
+func splitAtGaps(utterances []CleanUtterance, min float64) [][]CleanUtterance {
+	runs := [][]CleanUtterance{}
+	gaps := detectGaps(utterances, min)
+	start := 0
+	for i, u := range utterances {
+		// Each gap ends where the utterance opening the next run starts.
+		if len(gaps) > 0 && i > 0 && u.Start >= gaps[0].End {
+			runs = append(runs, utterances[start:i])
+			start = i
+			gaps = gaps[1:]
+		}
+	}
+	if len(utterances) > 0 {
+		runs = append(runs, utterances[start:])
+	}
+	return runs
+}
+
+// splitMeetings splits utterances into separate meetings at every silence longer
+// than gap seconds (see splitAtGaps). Utterances must be ordered by start time.
+func splitMeetings(utterances []CleanUtterance, gap float64) []Meeting {
+	meetings := []Meeting{}
+	for _, run := range splitAtGaps(utterances, gap) {
+		m := Meeting{Start: run[0].Start, End: run[0].End, Speakers: []string{}, Utterances: run}
+		seen := map[string]bool{}
+		for _, u := range run {
+			m.End = math.Max(m.End, u.End)
+			if u.Speaker != "" && !seen[u.Speaker] {
+				seen[u.Speaker] = true
+				m.Speakers = append(m.Speakers, u.Speaker)
+			}
+		}
+		meetings = append(meetings, m)
+	}
+	return meetings
+}
+
+// handleGetMeetings splits a long recording into the meetings it holds, separated by
+// silences longer than ?gap seconds (default 300).
+// If the transcription is not found, it returns a 404 error.
+func handleGetMeetings(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	gap := 300.0
+	if v := r.URL.Query().Get("gap"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "gap must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		gap = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(splitMeetings(data, gap))
+}
+
+// QualitySummary is an at-a-glance view of how reliable a transcript is.
+// LowConfidencePct is the percentage of utterances below Threshold.
+type QualitySummary struct {
+	Utterances        int     `json:"utterances"`
+	AverageConfidence float64 `json:"average_confidence"`
+	MinConfidence     float64 `json:"min_confidence"`
+	LowConfidencePct  float64 `json:"low_confidence_pct"`
+	Threshold         float64 `json:"threshold"`
+}
+
+// summarizeConfidence aggregates the utterance confidences of a transcript.
+// An empty transcript yields a summary with all values zero.
+func summarizeConfidence(utterances []CleanUtterance, threshold float64) QualitySummary {
+	q := QualitySummary{Utterances: len(utterances), Threshold: threshold}
+	if len(utterances) == 0 {
+		return q
+	}
+
+	var sum float64
+	low := 0
+	q.MinConfidence = utterances[0].Confidence
+	for _, u := range utterances {
+		sum += u.Confidence
+		if u.Confidence < q.MinConfidence {
+			q.MinConfidence = u.Confidence
+		}
+		if u.Confidence < threshold {
+			low++
+		}
+	}
+	q.AverageConfidence = sum / float64(len(utterances))
+	q.LowConfidencePct = float64(low) * 100 / float64(len(utterances))
+	return q
+}
+
+// markLowConfidence sets LowConfidence on every utterance whose confidence is below threshold.
+func markLowConfidence(utterances []CleanUtterance, threshold float64) {
+	for i := range utterances {
+		utterances[i].LowConfidence = utterances[i].Confidence < threshold
+	}
+}
+
+// lowConfidenceUtterances returns the utterances whose confidence is below threshold, in order.
+func lowConfidenceUtterances(utterances []CleanUtterance, threshold float64) []CleanUtterance {
+	out := []CleanUtterance{}
+	for _, u := range utterances {
+		if u.Confidence < threshold {
+			u.LowConfidence = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// handleGetReview returns only the utterances below LOW_CONFIDENCE_THRESHOLD,
+// so a reviewer can go straight to the unreliable parts of a transcript.
+// If the transcription is not found, it returns a 404 error.
+func handleGetReview(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lowConfidenceUtterances(data, cfg.LowConfidenceThreshold))
+}
+
+// handleGetQuality returns the confidence summary of a transcription.
+// If the transcription is not found, it returns a 404 error.
+func handleGetQuality(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeConfidence(data, cfg.LowConfidenceThreshold))
+}
+
+// Segment is a fixed time window of a transcript.
+// Utterances are assigned to the window containing their start time.
+// Speakers counts the utterances of each speaker label in the window.
+type Segment struct {
+	Start      float64        `json:"start"`
+	End        float64        `json:"end"`
+	Text       string         `json:"text"`
+	Utterances int            `json:"utterances"`
+	Speakers   map[string]int `json:"speakers"`
+	// Summary is the one-line LLM summary, only set with ?summaries=true.
+	Summary string `json:"summary,omitempty"`
+}
+
+// minWindowSeconds and maxWindows bound the ?window of the windowed analyses,
+// so a tiny window cannot make a response allocate millions of windows.
+const (
+	minWindowSeconds = 1.0
+	maxWindows       = 10000
+)
+
+// parseWindow reads a ?window length in seconds for analyses over utterances.
+// An empty value returns def. The window must be at least minWindowSeconds and
+// split the transcript, up to its latest end, into at most maxWindows windows.
+func parseWindow(v string, def float64, utterances []CleanUtterance) (float64, error) {
+	if v == "" {
+		return def, nil
+	}
+	window, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(window) || math.IsInf(window, 0) || window < minWindowSeconds {
+		return 0, fmt.Errorf("window must be a number of seconds, at least %g", minWindowSeconds)
+	}
+	end := 0.0
+	for _, u := range utterances {
+		end = math.Max(end, u.End)
+	}
+	if end/window >= maxWindows {
+		return 0, fmt.Errorf("window too small: this transcript would need more than %d windows", maxWindows)
+	}
+	return window, nil
+}
+
+// segmentByWindow groups utterances into consecutive windows of the given length in seconds.
+// Windows run from zero up to the window holding the last utterance start,
+// including empty windows so the result forms a continuous series.
+func segmentByWindow(utterances []CleanUtterance, window float64) []Segment {
+	segments := []Segment{}
+	if len(utterances) == 0 || window <= 0 {
+		return segments
+	}
+
+	last := 0
+	for _, u := range utterances {
+		if i := int(math.Floor(u.Start / window)); i > last {
+			last = i
+		}
+	}
+
+	texts := make([][]string, last+1)
+	for i := 0; i <= last; i++ {
+		segments = append(segments, Segment{Start: float64(i) * window, End: float64(i+1) * window, Speakers: map[string]int{}})
+	}
+	for _, u := range utterances {
+		i := int(math.Floor(u.Start / window))
+		if i < 0 {
+			i = 0
+		}
+		segments[i].Utterances++
+		if u.Speaker != "" {
+			segments[i].Speakers[u.Speaker]++
+		}
+		texts[i] = append(texts[i], u.Text)
+	}
+	for i := range segments {
+		segments[i].Text = strings.Join(texts[i], " ")
+	}
+	return segments
+}
+
+// handleGetSegments returns a transcription grouped into windows of ?window seconds (default 300),
+// bounded by parseWindow.
+// With ?summaries=true, each segment carries a one-line summary; see sectionSummaries.
+// If the transcription is not found, it returns a 404 error.
+func handleGetSegments(w http.ResponseWriter, r *http.Request) {
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return
+	}
+	data := entry.Utterances
+
+	window, err := parseWindow(r.URL.Query().Get("window"), 300, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	segments := segmentByWindow(data, window)
+	if r.URL.Query().Get("summaries") == "true" {
+		texts := make([]string, len(segments))
+		for i, s := range segments {
+			texts[i] = s.Text
+		}
+		summaries, ok := sectionSummaries(w, r, mux.Vars(r)["id"], entry, texts)
+		if !ok {
+			return
+		}
+		for i := range segments {
+			segments[i].Summary = summaries[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(segments)
+}
+
+// defaultStopwords are common English words left out of word frequency reports.
+var defaultStopwords = []string{
+	"a", "about", "an", "and", "are", "as", "at", "be", "but", "by", "can", "do", "for",
+	"from", "have", "he", "i", "i'm", "if", "in", "is", "it", "it's", "just", "know",
+	"like", "me", "my", "not", "of", "on", "or", "so", "that", "the", "their", "there",
+	"they", "this", "to", "uh", "um", "was", "we", "what", "with", "you", "your",
+}
+
+// loadStopwords reads one stopword per line from path.
+// When path is empty, it returns defaultStopwords.
+func loadStopwords(path string) (map[string]bool, error) {
+	words := defaultStopwords
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		words = nil
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if w := strings.TrimSpace(sc.Text()); w != "" {
+				words = append(words, w)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(w)] = true
+	}
+	return set, nil
+}
+
+// WordCount is the number of times a word occurs in a transcript.
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// tokenize splits text into lowercased words, keeping inner apostrophes.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	words := fields[:0]
+	for _, f := range fields {
+		if f = strings.Trim(f, "'"); f != "" {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// wordFrequencies counts the words of all utterances, skipping stopwords.
+// The result is ordered by descending count, then alphabetically.
+func wordFrequencies(utterances []CleanUtterance, stopwords map[string]bool) []WordCount {
+	counts := make(map[string]int)
+	for _, u := range utterances {
+		for _, w := range tokenize(u.Text) {
+			if !stopwords[w] {
+				counts[w]++
+			}
+		}
+	}
+
+	freqs := make([]WordCount, 0, len(counts))
+	for w, c := range counts {
+		freqs = append(freqs, WordCount{Word: w, Count: c})
+	}
+	sort.Slice(freqs, func(i, j int) bool {
+		if freqs[i].Count != freqs[j].Count {
+			return freqs[i].Count > freqs[j].Count
+		}
+		return freqs[i].Word < freqs[j].Word
+	})
+	return freqs
+}
+
+// handleGetWordFrequencies returns the ?top most frequent words of a transcription (default 50).
+// If the transcription is not found, it returns a 404 error.
+func handleGetWordFrequencies(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	top := 50
+	if v := r.URL.Query().Get("top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		top = parsed
+	}
+
+	freqs := wordFrequencies(data, cfg.Stopwords)
+	if len(freqs) > top {
+		freqs = freqs[:top]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(freqs)
+}
+
+// SpeakerTalkTime is how much of a meeting one speaker spent talking.
+// TalkRatio is the share of the meeting duration they spoke for, and
+// TalkToListen divides their talk time by the time they were not talking.
+// TalkToListen is omitted when the speaker talked for the whole meeting.
+type SpeakerTalkTime struct {
+	Speaker       string   `json:"speaker"`
+	TalkSeconds   float64  `json:"talk_seconds"`
+	ListenSeconds float64  `json:"listen_seconds"`
+	TalkRatio     float64  `json:"talk_ratio"`
+	TalkToListen  *float64 `json:"talk_to_listen,omitempty"`
+}
+
+// talkTimeBySpeaker sums the speaking time of each speaker, in order of first appearance.
+// The meeting duration runs from the first utterance start to the last utterance end.
+// Utterances without a speaker label are counted under noSpeakerLabel.
+func talkTimeBySpeaker(utterances []CleanUtterance) []SpeakerTalkTime {
+	out := []SpeakerTalkTime{}
+	if len(utterances) == 0 {
+		return out
+	}
+
+	start, end := utterances[0].Start, utterances[0].End
+	index := make(map[string]int)
+	for _, u := range utterances {
+		start = math.Min(start, u.Start)
+		end = math.Max(end, u.End)
+
+		label := u.Speaker
+		if label == "" {
+			label = noSpeakerLabel
+		}
+		i, ok := index[label]
+		if !ok {
+			i = len(out)
+			index[label] = i
+			out = append(out, SpeakerTalkTime{Speaker: label})
+		}
+		out[i].TalkSeconds += u.End - u.Start
+	}
+
+	total := end - start
+	for i := range out {
+		s := &out[i]
+		s.ListenSeconds = math.Max(total-s.TalkSeconds, 0)
+		if total > 0 {
+			s.TalkRatio = s.TalkSeconds / total
+		}
+		if s.ListenSeconds > 0 {
+			ratio := s.TalkSeconds / s.ListenSeconds
+			s.TalkToListen = &ratio
+		}
+	}
+	return out
+}
+
+// handleGetTalkTime returns the talk-to-listen balance of each speaker.
+// If the transcription is not found, it returns a 404 error.
+func handleGetTalkTime(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(talkTimeBySpeaker(data))
+}
+
+// EngagementWindow holds the activity metrics of one fixed time window.
+// SpeakerTurns counts the utterances in the window that start a new turn,
+// that is whose speaker differs from the speaker of the utterance before.
+type EngagementWindow struct {
+	Start          float64 `json:"start"`
+	End            float64 `json:"end"`
+	Words          int     `json:"words"`
+	WordsPerMinute float64 `json:"words_per_minute"`
+	SpeakerTurns   int     `json:"speaker_turns"`
+}
+
+// engagementByWindow computes the engagement curve of a transcript over windows of the given length in seconds.
+// Words are placed by their own start time when the utterance has word timings,
+// and by the utterance start otherwise. Like segmentByWindow, windows run from zero
+// up to the last occupied window, including empty ones.
+func engagementByWindow(utterances []CleanUtterance, window float64) []EngagementWindow {
+	windows := []EngagementWindow{}
+	if len(utterances) == 0 || window <= 0 {
+		return windows
+	}
+
+	index := func(t float64) int {
+		return max(int(math.Floor(t/window)), 0)
+	}
+	grow := func(i int) {
+		for len(windows) <= i {
+			n := float64(len(windows))
+			windows = append(windows, EngagementWindow{Start: n * window, End: (n + 1) * window})
+		}
+	}
+
+	prev := ""
+	for i, u := range utterances {
+		if i == 0 || u.Speaker != prev {
+			w := index(u.Start)
+			grow(w)
+			windows[w].SpeakerTurns++
+		}
+		prev = u.Speaker
+
+		if len(u.Words) == 0 {
+			w := index(u.Start)
+			grow(w)
+			windows[w].Words += len(tokenize(u.Text))
+			continue
+		}
+		for _, word := range u.Words {
+			w := index(word.Start)
+			grow(w)
+			windows[w].Words++
+		}
+	}
+
+	for i := range windows {
+		windows[i].WordsPerMinute = float64(windows[i].Words) * 60 / window
+	}
+	return windows
+}
+
+// handleGetEngagement returns the engagement curve of a transcription over windows of ?window seconds (default 60),
+// bounded by parseWindow.
+// If the transcription is not found, it returns a 404 error.
+func handleGetEngagement(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	window, err := parseWindow(r.URL.Query().Get("window"), 60, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(engagementByWindow(data, window))
+}
+
+// InteractionEdge counts how often Speaker To took the floor right after Speaker From.
+type InteractionEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Weight int    `json:"weight"`
+}
+
+// InteractionGraph is the who-responded-to-whom graph of a meeting.
+// Speakers are the nodes, in order of first appearance.
+type InteractionGraph struct {
+	Speakers []string          `json:"speakers"`
+	Edges    []InteractionEdge `json:"edges"`
+}
+
+// interactionGraph counts the transitions between consecutive speakers, so
+// A, B, A, B yields A->B twice and B->A once. Consecutive utterances of the same
+// speaker are one turn, and unlabeled utterances are skipped.
+// Edges are ordered by their first occurrence.
+func interactionGraph(utterances []CleanUtterance) InteractionGraph {
+	graph := InteractionGraph{Speakers: []string{}, Edges: []InteractionEdge{}}
+	seen := make(map[string]bool)
+	edges := make(map[[2]string]int)
+	prev := ""
+	for _, u := range utterances {
+		if u.Speaker == "" {
+			continue
+		}
+		if !seen[u.Speaker] {
+			seen[u.Speaker] = true
+			graph.Speakers = append(graph.Speakers, u.Speaker)
+		}
+		if prev != "" && prev != u.Speaker {
+			key := [2]string{prev, u.Speaker}
+			i, ok := edges[key]
+			if !ok {
+				i = len(graph.Edges)
+				edges[key] = i
+				graph.Edges = append(graph.Edges, InteractionEdge{From: prev, To: u.Speaker})
+			}
+			graph.Edges[i].Weight++
+		}
+		prev = u.Speaker
+	}
+	return graph
+}
+
+// handleGetInteractions returns the speaker interaction graph of a transcription.
+// It returns 422 when the transcription has no speaker labels, and a 404 error
+// if the transcription is not found.
+func handleGetInteractions(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	graph := interactionGraph(data)
+	if len(graph.Speakers) == 0 && len(data) > 0 {
+		http.Error(w, "Speaker labels not available", http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph)
+}
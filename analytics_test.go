@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDetectGaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		utterances []CleanUtterance
+		min        float64
+		want       []Gap
+	}{
+		{"empty", nil, 2, []Gap{}},
+		{"single", []CleanUtterance{{Start: 0, End: 1}}, 2, []Gap{}},
+		{"gap over min", []CleanUtterance{{Start: 0, End: 1}, {Start: 4, End: 5}}, 2, []Gap{{Start: 1, End: 4, Duration: 3}}},
+		{"gap at min is not reported", []CleanUtterance{{Start: 0, End: 1}, {Start: 3, End: 4}}, 2, []Gap{}},
+		{"overlap hides gap", []CleanUtterance{{Start: 0, End: 10}, {Start: 2, End: 3}, {Start: 11, End: 12}}, 2, []Gap{}},
+		{"measured from latest end", []CleanUtterance{{Start: 0, End: 10}, {Start: 2, End: 3}, {Start: 13, End: 14}}, 2, []Gap{{Start: 10, End: 13, Duration: 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectGaps(tt.utterances, tt.min); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectGaps = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleGetGaps(t *testing.T) {
+	storeTestTranscription(t, "gaps-1", []CleanUtterance{{Start: 0, End: 1}, {Start: 2, End: 3}, {Start: 10, End: 11}})
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantGaps int
+	}{
+		{"", http.StatusOK, 1},
+		{"min=0.5", http.StatusOK, 2},
+		{"min=-1", http.StatusBadRequest, 0},
+		{"min=abc", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		w := serveTranscription(handleGetGaps, "gaps-1", tt.query)
+		if w.Code != tt.wantCode {
+			t.Errorf("%q: status = %d, want %d", tt.query, w.Code, tt.wantCode)
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+		var gaps []Gap
+		if err := json.Unmarshal(w.Body.Bytes(), &gaps); err != nil {
+			t.Fatal(err)
+		}
+		if len(gaps) != tt.wantGaps {
+			t.Errorf("%q: %d gaps, want %d", tt.query, len(gaps), tt.wantGaps)
+		}
+	}
+
+	if w := serveTranscription(handleGetGaps, "missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing transcription: status = %d, want 404", w.Code)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	hour := []CleanUtterance{{Start: 0, End: 3600}}
+	tests := []struct {
+		name    string
+		v       string
+		want    float64
+		wantErr bool
+	}{
+		{"default", "", 300, false},
+		{"valid", "60", 60, false},
+		{"fractional", "1.5", 1.5, false},
+		{"below minimum", "0.5", 0, true},
+		{"zero", "0", 0, true},
+		{"negative", "-60", 0, true},
+		{"not a number", "abc", 0, true},
+		{"NaN", "NaN", 0, true},
+		{"infinite", "+Inf", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWindow(tt.v, 300, hour)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	long := []CleanUtterance{{Start: 0, End: maxWindows * 2}}
+	if _, err := parseWindow("1", 300, long); err == nil {
+		t.Error("want an error for a window splitting the transcript into more than maxWindows")
+	}
+	if _, err := parseWindow("2.5", 300, long); err != nil {
+		t.Errorf("window within maxWindows: %v", err)
+	}
+}
+
+func TestSegmentByWindow(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "hello", Start: 0, End: 5},
+		{Speaker: "B", Text: "hi", Start: 8, End: 9},
+		{Speaker: "A", Text: "later", Start: 25, End: 26},
+	}
+	got := segmentByWindow(utterances, 10)
+
+	want := []Segment{
+		{Start: 0, End: 10, Text: "hello hi", Utterances: 2, Speakers: map[string]int{"A": 1, "B": 1}},
+		{Start: 10, End: 20, Text: "", Utterances: 0, Speakers: map[string]int{}},
+		{Start: 20, End: 30, Text: "later", Utterances: 1, Speakers: map[string]int{"A": 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("segmentByWindow = %+v, want %+v", got, want)
+	}
+	if got := segmentByWindow(nil, 10); len(got) != 0 {
+		t.Errorf("no utterances: got %+v", got)
+	}
+}
+
+func TestHandleGetSegmentsRejectsTinyWindow(t *testing.T) {
+	storeTestTranscription(t, "segments-1", []CleanUtterance{{Text: "x", Start: 0, End: 36000}})
+
+	if w := serveTranscription(handleGetSegments, "segments-1", "window=0.001"); w.Code != http.StatusBadRequest {
+		t.Errorf("tiny window: status = %d, want 400", w.Code)
+	}
+	if w := serveTranscription(handleGetSegments, "segments-1", "window=3600"); w.Code != http.StatusOK {
+		t.Errorf("valid window: status = %d, want 200", w.Code)
+	}
+}
+
+func TestEngagementByWindow(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "one two three", Start: 0, End: 3},
+		{Speaker: "A", Text: "four", Start: 30, End: 31},
+		{Speaker: "B", Text: "five six", Start: 50, End: 70, Words: []CleanWord{{Text: "five", Start: 50}, {Text: "six", Start: 65}}},
+	}
+	got := engagementByWindow(utterances, 60)
+
+	want := []EngagementWindow{
+		{Start: 0, End: 60, Words: 5, WordsPerMinute: 5, SpeakerTurns: 2},
+		{Start: 60, End: 120, Words: 1, WordsPerMinute: 1, SpeakerTurns: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("engagementByWindow = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandleGetEngagementRejectsTinyWindow(t *testing.T) {
+	storeTestTranscription(t, "engagement-1", []CleanUtterance{{Text: "x", Start: 0, End: 36000}})
+
+	if w := serveTranscription(handleGetEngagement, "engagement-1", "window=1"); w.Code != http.StatusBadRequest {
+		t.Errorf("tiny window: status = %d, want 400", w.Code)
+	}
+	if w := serveTranscription(handleGetEngagement, "engagement-1", ""); w.Code != http.StatusOK {
+		t.Errorf("default window: status = %d, want 200", w.Code)
+	}
+}
+
+func TestTalkTimeBySpeaker(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Start: 0, End: 30},
+		{Speaker: "B", Start: 30, End: 40},
+		{Speaker: "", Start: 40, End: 50},
+		{Speaker: "A", Start: 50, End: 100},
+	}
+	got := talkTimeBySpeaker(utterances)
+
+	wantSpeakers := []string{"A", "B", noSpeakerLabel}
+	wantTalk := []float64{80, 10, 10}
+	if len(got) != len(wantSpeakers) {
+		t.Fatalf("got %d speakers, want %d", len(got), len(wantSpeakers))
+	}
+	for i, s := range got {
+		if s.Speaker != wantSpeakers[i] || s.TalkSeconds != wantTalk[i] || s.ListenSeconds != 100-wantTalk[i] {
+			t.Errorf("speaker %d = %+v, want %s talking %vs", i, s, wantSpeakers[i], wantTalk[i])
+		}
+		if s.TalkRatio != wantTalk[i]/100 {
+			t.Errorf("%s talk ratio = %v, want %v", s.Speaker, s.TalkRatio, wantTalk[i]/100)
+		}
+	}
+	if r := got[0].TalkToListen; r == nil || *r != 4 {
+		t.Errorf("A talk to listen = %v, want 4", r)
+	}
+
+	solo := talkTimeBySpeaker([]CleanUtterance{{Speaker: "A", Start: 0, End: 10}})
+	if solo[0].TalkToListen != nil {
+		t.Errorf("solo talk to listen = %v, want omitted", *solo[0].TalkToListen)
+	}
+	if got := talkTimeBySpeaker(nil); len(got) != 0 {
+		t.Errorf("no utterances: got %+v", got)
+	}
+}
+
+func TestInteractionGraph(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A"}, {Speaker: "B"}, {Speaker: "B"}, {Speaker: ""}, {Speaker: "A"}, {Speaker: "B"}, {Speaker: "C"},
+	}
+	got := interactionGraph(utterances)
+
+	want := InteractionGraph{
+		Speakers: []string{"A", "B", "C"},
+		Edges: []InteractionEdge{
+			{From: "A", To: "B", Weight: 2},
+			{From: "B", To: "A", Weight: 1},
+			{From: "B", To: "C", Weight: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("interactionGraph = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeConfidence(t *testing.T) {
+	tests := []struct {
+		name       string
+		confidence []float64
+		want       QualitySummary
+	}{
+		{"empty", nil, QualitySummary{Threshold: 0.5}},
+		{"all high", []float64{0.9, 0.7}, QualitySummary{Utterances: 2, AverageConfidence: 0.8, MinConfidence: 0.7, Threshold: 0.5}},
+		{"some low", []float64{0.2, 0.6, 0.4, 1}, QualitySummary{Utterances: 4, AverageConfidence: 0.55, MinConfidence: 0.2, LowConfidencePct: 50, Threshold: 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utterances := make([]CleanUtterance, len(tt.confidence))
+			for i, c := range tt.confidence {
+				utterances[i].Confidence = c
+			}
+			got := summarizeConfidence(utterances, 0.5)
+			if got.Utterances != tt.want.Utterances || got.MinConfidence != tt.want.MinConfidence ||
+				got.LowConfidencePct != tt.want.LowConfidencePct || got.Threshold != tt.want.Threshold ||
+				math.Abs(got.AverageConfidence-tt.want.AverageConfidence) > 1e-9 {
+				t.Errorf("summarizeConfidence = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMeetings(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Start: 0, End: 10},
+		{Speaker: "B", Start: 5, End: 20},
+		{Speaker: "A", Start: 400, End: 410},
+		{Speaker: "C", Start: 420, End: 430},
+		{Speaker: "C", Start: 1000, End: 1001},
+	}
+	got := splitMeetings(utterances, 300)
+
+	want := []struct {
+		start, end float64
+		speakers   []string
+		utterances int
+	}{
+		{0, 20, []string{"A", "B"}, 2},
+		{400, 430, []string{"A", "C"}, 2},
+		{1000, 1001, []string{"C"}, 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d meetings, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		w := want[i]
+		if m.Start != w.start || m.End != w.end || !reflect.DeepEqual(m.Speakers, w.speakers) || len(m.Utterances) != w.utterances {
+			t.Errorf("meeting %d = %v-%v %v with %d utterances, want %+v", i, m.Start, m.End, m.Speakers, len(m.Utterances), w)
+		}
+	}
+
+	if got := splitMeetings(nil, 300); len(got) != 0 {
+		t.Errorf("no utterances: got %+v", got)
+	}
+	if got := splitMeetings(utterances, 1e6); len(got) != 1 {
+		t.Errorf("no long gap: got %d meetings, want 1", len(got))
+	}
+}
+
+func TestWordFrequencies(t *testing.T) {
+	stopwords, err := loadStopwords("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utterances := []CleanUtterance{
+		{Text: "The Budget, the budget!"},
+		{Text: "'Budget' isn't final; it's the DEADLINE."},
+		{Text: "Deadline... again?"},
+	}
+
+	got := wordFrequencies(utterances, stopwords)
+	want := []WordCount{
+		{Word: "budget", Count: 3},
+		{Word: "deadline", Count: 2},
+		{Word: "again", Count: 1},
+		{Word: "final", Count: 1},
+		{Word: "isn't", Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wordFrequencies = %v, want %v", got, want)
+	}
+	if got := wordFrequencies(nil, stopwords); len(got) != 0 {
+		t.Errorf("wordFrequencies(nil) = %v, want empty", got)
+	}
+}
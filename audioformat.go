@@ -0,0 +1,40 @@
+package main
+
+import "bytes"
+
+// audioFormat is a recognized audio container, named by its file extension.
+type audioFormat struct {
+	Name      string
+	Extension string
+}
+
+// The containers detectAudioFormat recognizes.
+var (
+	formatWAV  = audioFormat{Name: "wav", Extension: ".wav"}
+	formatMP3  = audioFormat{Name: "mp3", Extension: ".mp3"}
+	formatOGG  = audioFormat{Name: "ogg", Extension: ".ogg"}
+	formatFLAC = audioFormat{Name: "flac", Extension: ".flac"}
+	formatMP4  = audioFormat{Name: "mp4", Extension: ".m4a"}
+)
+
+// detectAudioFormat identifies the container of audio from its first bytes.
+// It recognizes WAV (RIFF/WAVE), MP3 (an ID3 tag or an MPEG audio frame sync),
+// OGG, FLAC, and MP4/M4A (an ftyp box), and reports false for anything else.
+func detectAudioFormat(data []byte) (audioFormat, bool) {
+	switch {
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return formatWAV, true
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return formatMP3, true
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 && data[1]&0x06 != 0:
+		// An MPEG audio frame: 11 sync bits followed by a layer other than "reserved".
+		return formatMP3, true
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return formatOGG, true
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return formatFLAC, true
+	case len(data) >= 8 && bytes.Equal(data[4:8], []byte("ftyp")):
+		return formatMP4, true
+	}
+	return audioFormat{}, false
+}
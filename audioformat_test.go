@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestDetectAudioFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		want   audioFormat
+		wantOK bool
+	}{
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), formatWAV, true},
+		{"riff but not wave", []byte("RIFF\x24\x00\x00\x00AVI LIST"), audioFormat{}, false},
+		{"short riff", []byte("RIFF\x24\x00"), audioFormat{}, false},
+		{"mp3 id3 tag", []byte("ID3\x04\x00\x00"), formatMP3, true},
+		{"mp3 frame sync", []byte{0xFF, 0xFB, 0x90, 0x64}, formatMP3, true},
+		{"mpeg reserved layer", []byte{0xFF, 0xF1, 0x50, 0x80}, audioFormat{}, false},
+		{"ogg", []byte("OggS\x00\x02"), formatOGG, true},
+		{"flac", []byte("fLaC\x00\x00\x00\x22"), formatFLAC, true},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A "), formatMP4, true},
+		{"text", []byte("hello world"), audioFormat{}, false},
+		{"one byte", []byte{0xFF}, audioFormat{}, false},
+		{"empty", nil, audioFormat{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := detectAudioFormat(tt.data)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
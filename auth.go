@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// requestToken returns the bearer token of a request. WebSocket clients that
+// cannot set headers, such as browsers, may pass it as the token query parameter instead.
+func requestToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if r.URL.Path == "/ws" {
+		return r.URL.Query().Get("token")
+	}
+	return ""
+}
+
+// authorized reports whether token matches SERVICE_API_KEY.
+// With no key configured, every token is rejected.
+func authorized(token string) bool {
+	if cfg.ServiceAPIKey == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cfg.ServiceAPIKey)) == 1
+}
+
+// requireAuth is middleware that rejects requests without a valid bearer token
+// with 401 and a JSON body. It passes everything through when AUTH_DISABLED is set.
+// For the WebSocket, the check runs before the upgrade.
+func requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AuthDisabled || authorized(requestToken(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "missing or invalid API key"})
+	})
+}
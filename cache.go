@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// exportCache keeps rendered exports keyed by transcription ID and format.
+// Concurrent requests for the same missing export share a single render.
+// gens holds the generation of each ID, a fresh value from seq on every
+// invalidation, so a render of data loaded before an invalidation is neither
+// shared with later requests nor stored.
+type exportCache struct {
+	mu      sync.Mutex
+	entries map[string]map[string]string
+	gens    map[string]uint64
+	seq     uint64
+	group   singleflight.Group
+}
+
+// exports is the shared export cache, used when EXPORT_CACHE is enabled.
+var exports = &exportCache{entries: make(map[string]map[string]string), gens: make(map[string]uint64)}
+
+// generation returns the current generation of id. Handlers take it before
+// loading the transcription they render and pass it to get.
+func (c *exportCache) generation(id string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gens[id]
+}
+
+// get returns the cached export for id and format, rendering it on a miss.
+// gen is the generation of id taken before the rendered data was loaded;
+// the render is only cached if id was not invalidated since.
+// When the cache is disabled, it always renders.
+func (c *exportCache) get(id, format string, gen uint64, render func() string) string {
+	if !cfg.ExportCache {
+		return render()
+	}
+
+	c.mu.Lock()
+	out, ok := c.entries[id][format]
+	current := c.gens[id] == gen
+	c.mu.Unlock()
+	if ok && current {
+		return out
+	}
+
+	key := fmt.Sprintf("%s|%s|%d", id, format, gen)
+	v, _, _ := c.group.Do(key, func() (any, error) {
+		out := render()
+		c.mu.Lock()
+		if c.gens[id] == gen {
+			if c.entries[id] == nil {
+				c.entries[id] = make(map[string]string)
+			}
+			c.entries[id][format] = out
+		}
+		c.mu.Unlock()
+		return out, nil
+	})
+	return v.(string)
+}
+
+// invalidate drops every cached export of a transcription and bumps its generation.
+// It must be called whenever the stored transcription changes.
+func (c *exportCache) invalidate(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.seq++
+	c.gens[id] = c.seq
+	c.mu.Unlock()
+}
+
+// forget drops the cached exports and the generation of a transcription
+// that was removed from the store.
+func (c *exportCache) forget(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	delete(c.gens, id)
+	c.mu.Unlock()
+}
+
+// drop removes the cached exports of the given transcriptions.
+// It returns how many transcriptions had cached exports and an estimate
+// of the bytes freed, counting the rendered output only.
+func (c *exportCache) drop(ids []string) (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count, freed := 0, 0
+	for _, id := range ids {
+		formats, ok := c.entries[id]
+		if !ok {
+			continue
+		}
+		for _, out := range formats {
+			freed += len(out)
+		}
+		delete(c.entries, id)
+		count++
+	}
+	return count, freed
+}
+
+// compactExports drops the cached exports of transcriptions created more than
+// age before now. The stored results are kept, so a dropped export is simply
+// rendered again on its next request.
+func compactExports(age time.Duration, now time.Time) (int, int) {
+	return exports.drop(idsCreatedBefore(now.Add(-age)))
+}
+
+// runCompactor compacts the export cache every interval until ctx is cancelled.
+func runCompactor(ctx context.Context, age, interval time.Duration, clock Clock) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			if n, freed := compactExports(age, clock.Now()); n > 0 {
+				slog.Info("Compacted export cache", "transcriptions", n, "freed_bytes", freed)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExportCacheSkipsStaleRender(t *testing.T) {
+	useConfig(t, func(c *config) { c.ExportCache = true })
+	c := &exportCache{entries: make(map[string]map[string]string), gens: make(map[string]uint64)}
+
+	// A render of data loaded before an invalidation is served but not cached.
+	gen := c.generation("a")
+	c.invalidate("a")
+	if got := c.get("a", "srt", gen, func() string { return "old" }); got != "old" {
+		t.Fatalf("got %q, want the stale render served once", got)
+	}
+	if got := c.get("a", "srt", c.generation("a"), func() string { return "new" }); got != "new" {
+		t.Errorf("got %q, want a fresh render, not the stale one", got)
+	}
+
+	// A current render is cached until the next invalidation.
+	renders := 0
+	render := func() string { renders++; return "new" }
+	gen = c.generation("a")
+	c.get("a", "srt", gen, render)
+	c.get("a", "srt", gen, render)
+	if renders != 0 {
+		t.Errorf("renders = %d, want the cached export reused", renders)
+	}
+	c.invalidate("a")
+	c.get("a", "srt", c.generation("a"), render)
+	if renders != 1 {
+		t.Errorf("renders after invalidate = %d, want 1", renders)
+	}
+}
+
+func TestExportCacheForgetStopsLateStore(t *testing.T) {
+	useConfig(t, func(c *config) { c.ExportCache = true })
+	c := &exportCache{entries: make(map[string]map[string]string), gens: make(map[string]uint64)}
+
+	c.invalidate("a")
+	gen := c.generation("a")
+	c.get("a", "srt", gen, func() string {
+		// The transcription is deleted while it is being rendered.
+		c.forget("a")
+		return "deleted"
+	})
+	if _, ok := c.entries["a"]; ok {
+		t.Error("render of a deleted transcription was cached")
+	}
+}
+
+func TestCompactExportsKeepsResults(t *testing.T) {
+	useConfig(t, func(c *config) { c.ExportCache = true })
+	useEmptyStore(t)
+	prev := exports
+	exports = &exportCache{entries: make(map[string]map[string]string), gens: make(map[string]uint64)}
+	t.Cleanup(func() { exports = prev })
+
+	saveTranscription("old", transcriptEntry{Utterances: []CleanUtterance{{Text: "hi"}}, CreatedAt: testEpoch.Add(-2 * time.Hour)})
+	saveTranscription("new", transcriptEntry{Utterances: []CleanUtterance{{Text: "hi"}}, CreatedAt: testEpoch})
+	for _, id := range []string{"old", "new"} {
+		exports.get(id, "srt", exports.generation(id), func() string { return "srt!" })
+		exports.get(id, "vtt", exports.generation(id), func() string { return "vtt" })
+	}
+
+	n, freed := compactExports(time.Hour, testEpoch)
+	if n != 1 || freed != len("srt!")+len("vtt") {
+		t.Errorf("compactExports = %d, %d, want 1, %d", n, freed, len("srt!")+len("vtt"))
+	}
+	if _, ok := exports.entries["old"]; ok {
+		t.Error("exports of old are still cached")
+	}
+	if len(exports.entries["new"]) != 2 {
+		t.Errorf("new has %d cached exports, want 2", len(exports.entries["new"]))
+	}
+	for _, id := range []string{"old", "new"} {
+		if entry, ok, err := getTranscription(id); !ok || err != nil || len(entry.Utterances) != 1 {
+			t.Errorf("%s: stored = %v, %v, want the result kept", id, ok, err)
+		}
+	}
+	if got := exports.get("old", "srt", exports.generation("old"), func() string { return "rendered" }); got != "rendered" {
+		t.Errorf("old srt = %q, want it rendered again", got)
+	}
+
+	if n, freed := compactExports(time.Hour, testEpoch); n != 1 || freed != len("rendered") {
+		t.Errorf("second compaction = %d, %d, want 1, %d", n, freed, len("rendered"))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// loadBlocklist reads one blocked word per line from path, lowercased.
+// Blank lines and lines starting with # are skipped.
+func loadBlocklist(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	words := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if w := strings.TrimSpace(sc.Text()); w != "" && !strings.HasPrefix(w, "#") {
+			words[strings.ToLower(w)] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// maskWord keeps the first letter of word and replaces the rest with asterisks,
+// the way AssemblyAI's profanity filter does, so "darn" becomes "d***".
+func maskWord(word string) string {
+	runes := []rune(word)
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+}
+
+// maskBlockedWords masks every word of text found in blocklist, ignoring case
+// and surrounding punctuation. Text without a blocked word is returned unchanged.
+func maskBlockedWords(text string, blocklist map[string]bool) string {
+	fields := strings.Fields(text)
+	changed := false
+	for i, f := range fields {
+		t := splitToken(f)
+		if t.word != "" && blocklist[strings.ToLower(t.word)] {
+			fields[i] = t.lead + maskWord(t.word) + t.trail
+			changed = true
+		}
+	}
+	if !changed {
+		return text
+	}
+	return strings.Join(fields, " ")
+}
+
+// maskUtterances applies maskBlockedWords to the text, original text, and words of every utterance.
+func maskUtterances(utterances []CleanUtterance, blocklist map[string]bool) {
+	for i := range utterances {
+		u := &utterances[i]
+		u.Text = maskBlockedWords(u.Text, blocklist)
+		u.OriginalText = maskBlockedWords(u.OriginalText, blocklist)
+		for j := range u.Words {
+			u.Words[j].Text = maskBlockedWords(u.Words[j].Text, blocklist)
+		}
+	}
+}
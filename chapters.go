@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"github.com/gorilla/mux"
+)
+
+// Chapter is an automatically detected chapter of a transcription.
+// Start and end are in seconds. Source is chapterSourceLocal for chapters
+// derived by this server and empty for the provider's.
+type Chapter struct {
+	Headline string  `json:"headline"`
+	Gist     string  `json:"gist"`
+	Summary  string  `json:"summary"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Source   string  `json:"source,omitempty"`
+}
+
+// chapterSourceLocal marks chapters derived from utterance gaps instead of by the provider.
+const chapterSourceLocal = "local"
+
+// localHeadlineWords is how many leading words of a local chapter form its headline.
+const localHeadlineWords = 8
+
+// toChapters converts AssemblyAI's chapters, turning the millisecond timestamps into seconds.
+func toChapters(chapters []assemblyai.Chapter) []Chapter {
+	out := make([]Chapter, len(chapters))
+	for i, c := range chapters {
+		out[i] = Chapter{
+			Headline: assemblyai.ToString(c.Headline),
+			Gist:     assemblyai.ToString(c.Gist),
+			Summary:  assemblyai.ToString(c.Summary),
+			Start:    float64(assemblyai.ToInt64(c.Start)) / 1000.0,
+			End:      float64(assemblyai.ToInt64(c.End)) / 1000.0,
+		}
+	}
+	return out
+}
+
+// utterancesInRange returns the utterances starting at or after start and before end,
+// so an utterance that runs over a boundary belongs to the range it starts in.
+func utterancesInRange(utterances []CleanUtterance, start, end float64) []CleanUtterance {
+	out := []CleanUtterance{}
+	for _, u := range utterances {
+		if u.Start >= start && u.Start < end {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// localChapters derives coarse chapters from the pauses in a transcription:
+// a new chapter starts after every silence longer than minGap seconds, as split by splitAtGaps.
+// Each chapter's summary is its first utterance, and its headline the first words of it.
+func localChapters(utterances []CleanUtterance, minGap float64) []Chapter {
+	chapters := []Chapter{}
+	for _, run := range splitAtGaps(utterances, minGap) {
+		text := strings.TrimSpace(run[0].Text)
+		headline := strings.Fields(text)
+		if len(headline) > localHeadlineWords {
+			headline = headline[:localHeadlineWords]
+		}
+		c := Chapter{
+			Headline: strings.Join(headline, " "),
+			Summary:  text,
+			Start:    run[0].Start,
+			Source:   chapterSourceLocal,
+		}
+		for _, u := range run {
+			c.End = math.Max(c.End, u.End)
+		}
+		chapters = append(chapters, c)
+	}
+	return chapters
+}
+
+// chapterText joins the text of the utterances starting within c.
+func chapterText(utterances []CleanUtterance, c Chapter) string {
+	in := utterancesInRange(utterances, c.Start, c.End)
+	texts := make([]string, len(in))
+	for i, u := range in {
+		texts[i] = u.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// loadChapters looks up the chapters of the transcription named by the {id} route variable.
+// If chapters were not requested, it derives them locally when LOCAL_CHAPTERS is enabled,
+// and otherwise writes a 404 error and returns false.
+func loadChapters(w http.ResponseWriter, r *http.Request) (transcriptEntry, bool) {
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return entry, false
+	}
+	if entry.Chapters == nil && cfg.LocalChapters {
+		entry.Chapters = localChapters(entry.Utterances, cfg.LocalChapterGapSeconds)
+	}
+	if entry.Chapters == nil {
+		http.Error(w, "Chapters were not requested for this transcription", http.StatusNotFound)
+		return entry, false
+	}
+	return entry, true
+}
+
+// handleGetChapters returns the chapters detected in a transcription.
+// Chapter detection must have been requested with ?chapters=true on upload,
+// unless LOCAL_CHAPTERS derives chapters from pauses.
+// With ?summaries=true, chapters without a gist, such as local ones, get a one-line
+// LLM summary as their gist; see sectionSummaries.
+func handleGetChapters(w http.ResponseWriter, r *http.Request) {
+	entry, ok := loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	chapters := entry.Chapters
+	if r.URL.Query().Get("summaries") == "true" {
+		chapters = slices.Clone(chapters)
+		texts := make([]string, len(chapters))
+		for i, c := range chapters {
+			if c.Gist == "" {
+				texts[i] = chapterText(entry.Utterances, c)
+			}
+		}
+		summaries, ok := sectionSummaries(w, r, mux.Vars(r)["id"], entry, texts)
+		if !ok {
+			return
+		}
+		for i := range chapters {
+			if summaries[i] != "" {
+				chapters[i].Gist = summaries[i]
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(chapters)
+}
+
+// handleGetChapterUtterances returns the utterances of the chapter at the
+// zero-based {index}. It returns 400 for an index outside the chapter list.
+func handleGetChapterUtterances(w http.ResponseWriter, r *http.Request) {
+	entry, ok := loadChapters(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil || index < 0 || index >= len(entry.Chapters) {
+		http.Error(w, "index must be between 0 and "+strconv.Itoa(len(entry.Chapters)-1), http.StatusBadRequest)
+		return
+	}
+	c := entry.Chapters[index]
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(utterancesInRange(entry.Utterances, c.Start, c.End))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// serveChapter calls handleGetChapterUtterances for chapter index of transcription id.
+func serveChapter(id, index string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/transcription/"+id+"/chapters/"+index+"/utterances", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id, "index": index})
+	w := httptest.NewRecorder()
+	handleGetChapterUtterances(w, r)
+	return w
+}
+
+func TestHandleGetChapterUtterances(t *testing.T) {
+	useConfig(t, func(c *config) { c.LocalChapters = false })
+	const id = "chapters-1"
+	saveTranscription(id, transcriptEntry{
+		Utterances: []CleanUtterance{
+			{Text: "intro", Start: 0, End: 4},
+			{Text: "runs over", Start: 9, End: 12},
+			{Text: "budget", Start: 10, End: 15},
+			{Text: "wrap up", Start: 20, End: 22},
+		},
+		Chapters: []Chapter{{Headline: "Intro", Start: 0, End: 10}, {Headline: "Budget", Start: 10, End: 25}},
+	})
+	t.Cleanup(func() { deleteTranscription(id) })
+
+	tests := []struct {
+		index string
+		want  []string
+	}{
+		{"0", []string{"intro", "runs over"}},
+		{"1", []string{"budget", "wrap up"}},
+	}
+	for _, tt := range tests {
+		w := serveChapter(id, tt.index)
+		if w.Code != http.StatusOK {
+			t.Fatalf("index %s: status = %d, want 200: %s", tt.index, w.Code, w.Body)
+		}
+		var got []CleanUtterance
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(texts(got), tt.want) {
+			t.Errorf("index %s: got %v, want %v", tt.index, texts(got), tt.want)
+		}
+	}
+
+	for _, index := range []string{"2", "-1", "first"} {
+		if w := serveChapter(id, index); w.Code != http.StatusBadRequest {
+			t.Errorf("index %s: status = %d, want 400", index, w.Code)
+		}
+	}
+}
+
+func TestHandleGetChapterUtterancesWithoutChapters(t *testing.T) {
+	storeTestTranscription(t, "chapters-none", []CleanUtterance{
+		{Text: "before", Start: 0, End: 1},
+		{Text: "after", Start: 30, End: 31},
+	})
+	saveTranscription("chapters-empty", transcriptEntry{Utterances: []CleanUtterance{{Text: "hi"}}, Chapters: []Chapter{}})
+	t.Cleanup(func() { deleteTranscription("chapters-empty") })
+
+	useConfig(t, func(c *config) { c.LocalChapters = false })
+	if w := serveChapter("chapters-none", "0"); w.Code != http.StatusNotFound {
+		t.Errorf("not requested: status = %d, want 404", w.Code)
+	}
+	if w := serveChapter("chapters-empty", "0"); w.Code != http.StatusBadRequest {
+		t.Errorf("no chapters detected: status = %d, want 400", w.Code)
+	}
+
+	useConfig(t, func(c *config) { c.LocalChapters, c.LocalChapterGapSeconds = true, 10 })
+	w := serveChapter("chapters-none", "1")
+	var got []CleanUtterance
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("local chapter: status = %d, err %v", w.Code, err)
+	}
+	if !reflect.DeepEqual(texts(got), []string{"after"}) {
+		t.Errorf("local chapter 1 = %v, want [after]", texts(got))
+	}
+}
+
+func TestLocalChapters(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "  one two three four five six seven eight nine ten ", Start: 0, End: 5},
+		{Text: "overlapping and longer", Start: 4, End: 9},
+		{Text: "after exactly the gap", Start: 19, End: 20},
+		{Text: "Next topic.", Start: 31, End: 33},
+	}
+
+	got := localChapters(utterances, 10)
+	want := []Chapter{
+		{Headline: "one two three four five six seven eight", Summary: "one two three four five six seven eight nine ten", Start: 0, End: 20, Source: chapterSourceLocal},
+		{Headline: "Next topic.", Summary: "Next topic.", Start: 31, End: 33, Source: chapterSourceLocal},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("localChapters =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := localChapters(nil, 10); got == nil || len(got) != 0 {
+		t.Errorf("localChapters(nil) = %#v, want empty", got)
+	}
+}
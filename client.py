@@ -3,8 +3,12 @@ import json
 import argparse
 import os
 import sys
+import time
 import requests
 
+CHUNK_BYTES = 1 << 20
+POLL_SECONDS = 3
+
 def format_txt_output(transcripts):
     lines = []
     for u in transcripts:
@@ -20,6 +24,7 @@ def main():
     parser.add_argument("--url", default="ws://localhost:8080/ws", help="WebSocket server URL")
     parser.add_argument("--api", default="http://localhost:8080/transcription", help="HTTP API to get transcription by ID")
     parser.add_argument("--output", choices=["uuid", "json", "txt"], default="json", help="Output format")
+    parser.add_argument("--token", default=os.environ.get("SERVICE_API_KEY"), help="Service API key (defaults to $SERVICE_API_KEY)")
     args = parser.parse_args()
 
     if not os.path.exists(args.filepath):
@@ -28,16 +33,30 @@ def main():
 
     try:
         print(f"[WS] Connecting to {args.url} ...")
-        ws = websocket.create_connection(args.url)
+        headers = {"Authorization": f"Bearer {args.token}"} if args.token else {}
+        ws = websocket.create_connection(args.url, header=headers)
 
         with open(args.filepath, "rb") as f:
             audio_data = f.read()
-            ws.send(audio_data, opcode=websocket.ABNF.OPCODE_BINARY)
+            # Large files go in chunks; "done" marks the end of the upload.
+            for i in range(0, len(audio_data), CHUNK_BYTES):
+                ws.send(audio_data[i:i + CHUNK_BYTES], opcode=websocket.ABNF.OPCODE_BINARY)
+            ws.send("done")
 
-        response = ws.recv()
+        # Uploads are transcribed in the background, so the reply carries the
+        # connection_id right away; with async=false, progress frames such as
+        # {"status": "processing"} arrive until the result.
+        while True:
+            ws_data = json.loads(ws.recv())
+            if "status" not in ws_data or "connection_id" in ws_data:
+                break
+            print(f"[WS] Status: {ws_data['status']}")
         ws.close()
 
-        ws_data = json.loads(response)
+        if "error" in ws_data:
+            print(f"Error: {ws_data['error']} ({ws_data.get('code')}): {ws_data.get('detail')}")
+            return
+
         connection_id = ws_data.get("connection_id")
         if not connection_id:
             print("Error: No connection_id returned.")
@@ -49,7 +68,11 @@ def main():
 
         api_url = f"{args.api}/{connection_id}"
         print(f"[HTTP] Getting full transcript from {api_url}")
-        resp = requests.get(api_url)
+        resp = requests.get(api_url, headers=headers)
+        # 202 means the transcription is still running.
+        while resp.status_code == 202:
+            time.sleep(POLL_SECONDS)
+            resp = requests.get(api_url, headers=headers)
         if resp.status_code != 200:
             print("Error retrieving transcription:", resp.text)
             return
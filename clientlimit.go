@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// clientLimiterIdle is how long a client's limiter is kept after its last submission.
+// A client idle for longer has a full bucket again anyway.
+const clientLimiterIdle = 10 * time.Minute
+
+// clientLimiter rate limits transcription submissions per client with one
+// token bucket each, refilled at perMinute tokens per minute up to burst.
+// Since all clients share the SERVICE_API_KEY, clients are told apart by IP.
+type clientLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*clientBucket
+}
+
+// clientBucket is the token bucket of one client and when it was last used.
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newClientLimiter creates a clientLimiter allowing perMinute submissions per minute
+// and client, with bursts of up to burst.
+func newClientLimiter(perMinute, burst int) *clientLimiter {
+	return &clientLimiter{
+		limit:    rate.Limit(float64(perMinute) / 60),
+		burst:    burst,
+		limiters: make(map[string]*clientBucket),
+	}
+}
+
+// allow takes a token from the bucket of client at now, reporting false when it is empty.
+func (l *clientLimiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.limiters[client]
+	if !ok {
+		b = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[client] = b
+	}
+	b.lastSeen = now
+	return b.limiter.AllowN(now, 1)
+}
+
+// sweep removes the buckets of clients idle for more than idle before now,
+// so the map doesn't grow with every client ever seen. It returns how many were removed.
+func (l *clientLimiter) sweep(idle time.Duration, now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for client, b := range l.limiters {
+		if now.Sub(b.lastSeen) > idle {
+			delete(l.limiters, client)
+			n++
+		}
+	}
+	return n
+}
+
+// runClientLimiterSweeper sweeps idle clients from l every interval until ctx is cancelled.
+func runClientLimiterSweeper(ctx context.Context, l *clientLimiter, interval time.Duration, clock Clock) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			l.sweep(clientLimiterIdle, clock.Now())
+		}
+	}
+}
+
+// submitLimiter limits transcription submissions per client, or is nil when
+// SUBMIT_RATE_PER_MINUTE is not set. It is created in main.
+var submitLimiter *clientLimiter
+
+// clientIP returns the IP address of the client of r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowSubmission reports whether the client of r may submit another transcription.
+func allowSubmission(r *http.Request) bool {
+	return submitLimiter == nil || submitLimiter.allow(clientIP(r), time.Now())
+}
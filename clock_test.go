@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeClock is a Clock whose time only moves when the test says so.
+// Waits started with After fire once Advance moves the time past their deadline.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the time forward by d and fires the waits that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// Set moves the time to t, which may be in the past, like a stepped wall clock.
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// blockUntilWaiters waits until n waits are pending, failing the test after a second.
+func (c *fakeClock) blockUntilWaiters(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		got := len(c.waiters)
+		c.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("waiters = %d, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var testEpoch = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func TestFakeClockAfter(t *testing.T) {
+	c := newFakeClock(testEpoch)
+	ch := c.After(time.Minute)
+
+	c.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("fired before its deadline")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case got := <-ch:
+		if want := testEpoch.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("fired at %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("did not fire at its deadline")
+	}
+}
+
+func TestServerTimestampNeverGoesBackwards(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	restore := storeClock
+	storeClock = clock
+	t.Cleanup(func() { storeClock, lastStamped = restore, time.Time{} })
+	lastStamped = time.Time{}
+
+	mu.Lock()
+	defer mu.Unlock()
+	first := serverTimestamp()
+	if !first.Equal(testEpoch) {
+		t.Fatalf("first = %v, want %v", first, testEpoch)
+	}
+
+	steps := []struct {
+		name string
+		move func()
+		want time.Time
+	}{
+		{"same instant", func() {}, testEpoch.Add(time.Nanosecond)},
+		{"stepped back", func() { clock.Set(testEpoch.Add(-time.Hour)) }, testEpoch.Add(2 * time.Nanosecond)},
+		{"moved forward", func() { clock.Set(testEpoch.Add(time.Second)) }, testEpoch.Add(time.Second)},
+	}
+	for _, step := range steps {
+		step.move()
+		if got := serverTimestamp(); !got.Equal(step.want) {
+			t.Errorf("%s: got %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestInFlightRegistryStampsStart(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	r := NewInFlightRegistry(clock)
+
+	r.Add("a", statusProcessing, nil)
+	clock.Advance(time.Minute)
+	r.Add("b", statusProcessing, nil)
+
+	for id, want := range map[string]time.Time{"a": testEpoch, "b": testEpoch.Add(time.Minute)} {
+		job, ok := r.Get(id)
+		if !ok {
+			t.Fatalf("%s not registered", id)
+		}
+		if !job.Started.Equal(want) {
+			t.Errorf("%s started at %v, want %v", id, job.Started, want)
+		}
+	}
+}
+
+func TestProviderHealthCachesPing(t *testing.T) {
+	useConfig(t, func(c *config) { c.ReadinessProviderTTL = 30 * time.Second })
+	pings := 0
+	restore := providerPing
+	providerPing = func(ctx context.Context, apiKey string) error {
+		pings++
+		return errors.New("unreachable")
+	}
+	t.Cleanup(func() { providerPing = restore })
+
+	clock := newFakeClock(testEpoch)
+	h := newProviderHealth(clock)
+
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		wantPings int
+	}{
+		{"first check pings", 0, 1},
+		{"within the TTL", 29 * time.Second, 1},
+		{"TTL passed", time.Second, 2},
+		{"cached again", 10 * time.Second, 2},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		if err := h.check(context.Background(), "key"); err == nil {
+			t.Errorf("%s: want the cached ping error", tt.name)
+		}
+		if pings != tt.wantPings {
+			t.Errorf("%s: pings = %d, want %d", tt.name, pings, tt.wantPings)
+		}
+	}
+}
+
+func TestFetchUtterancesRetriesEmptyAfterPollInterval(t *testing.T) {
+	useConfig(t, func(c *config) {
+		c.RetryEmptyUtterances = true
+		c.PollInterval = 3 * time.Second
+	})
+
+	clock := newFakeClock(testEpoch)
+	ft := &fakeTranscriber{utterances: [][]Utterance{nil, {{Text: "hello", Speaker: "A"}}}}
+
+	type result struct {
+		utterances []Utterance
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		u, err := fetchUtterances(context.Background(), clock, ft, "t1")
+		done <- result{u, err}
+	}()
+
+	clock.blockUntilWaiters(t, 1)
+	if got := ft.utteranceCalls(); got != 1 {
+		t.Fatalf("calls before the interval = %d, want 1", got)
+	}
+	clock.Advance(cfg.PollInterval)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(res.utterances) != 1 || res.utterances[0].Text != "hello" {
+		t.Errorf("utterances = %+v, want the retried result", res.utterances)
+	}
+	if got := ft.utteranceCalls(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestParseIngestOptionsChecksCreatedAtOnStoreClock(t *testing.T) {
+	restore := storeClock
+	storeClock = newFakeClock(testEpoch)
+	t.Cleanup(func() { storeClock = restore })
+	useConfig(t, func(c *config) { c.CreatedAtSource, c.ClientTimestampMaxSkew = createdAtClient, time.Hour })
+
+	tests := []struct {
+		createdAt time.Time
+		wantErr   bool
+	}{
+		{testEpoch.Add(30 * time.Minute), false},
+		{testEpoch.Add(-59 * time.Minute), false},
+		{testEpoch.Add(-2 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		opts, err := parseIngestOptions(url.Values{"created_at": {tt.createdAt.Format(time.RFC3339)}})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("created_at %v: err = %v, wantErr %v", tt.createdAt, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !opts.CreatedAt.Equal(tt.createdAt) {
+			t.Errorf("CreatedAt = %v, want %v", opts.CreatedAt, tt.createdAt)
+		}
+	}
+}
+
+// observedDurations returns the sample count and sum of transcriptionDuration.
+func observedDurations(t *testing.T) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	if err := transcriptionDuration.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+func TestTranscribeUploadTimesJobOnPollerClock(t *testing.T) {
+	const interval = time.Minute
+	clock := newFakeClock(testEpoch)
+	prev := jobPoller
+	ctx, cancel := context.WithCancel(context.Background())
+	jobPoller = newPoller(interval, 1, clock)
+	go jobPoller.run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		jobPoller = prev
+	})
+
+	const id = "timed-upload"
+	t.Cleanup(func() { deleteTranscription(id) })
+	ft := &fakeTranscriber{utterances: [][]Utterance{{{Speaker: "A", Text: "hello", End: 1000}}}}
+	count, sum := observedDurations(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := transcribeUpload(context.Background(), ft, strings.NewReader("audio"), id, &assemblyai.TranscriptOptionalParams{}, ingestOptions{}, nil)
+		done <- err
+	}()
+	for {
+		jobPoller.mu.Lock()
+		pending := len(jobPoller.jobs)
+		jobPoller.mu.Unlock()
+		if pending == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	clock.blockUntilWaiters(t, 1)
+	clock.Advance(interval)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	gotCount, gotSum := observedDurations(t)
+	if gotCount != count+1 {
+		t.Fatalf("observed %d durations, want %d", gotCount-count, 1)
+	}
+	if d := gotSum - sum; math.Abs(d-interval.Seconds()) > 1e-6 {
+		t.Errorf("observed duration = %vs, want %vs", d, interval.Seconds())
+	}
+}
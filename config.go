@@ -0,0 +1,401 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config holds the server settings read from the environment.
+type config struct {
+	// ListenAddr is the host:port the server binds to.
+	ListenAddr string
+	// ServiceAPIKey is the bearer token clients must send.
+	ServiceAPIKey string
+	// AuthDisabled turns off authentication, for local development.
+	AuthDisabled bool
+	// ShutdownGrace is how long in-flight requests may run after a shutdown signal.
+	ShutdownGrace time.Duration
+	// ReadinessProviderCheck enables pinging AssemblyAI from /readyz.
+	// It is off by default because each ping is an API call.
+	ReadinessProviderCheck bool
+	// ReadinessProviderTTL is how long a ping result is cached.
+	ReadinessProviderTTL time.Duration
+	// StoreCompress stores transcriptions as gzipped JSON to save memory.
+	StoreCompress bool
+	// StoreTTL is how long a transcription is kept after it is stored; zero, the default, keeps it forever.
+	StoreTTL time.Duration
+	// StoreSweepInterval is how often expired transcriptions are removed.
+	StoreSweepInterval time.Duration
+	// LowConfidenceThreshold is the confidence below which an utterance counts as unreliable.
+	LowConfidenceThreshold float64
+	// PollInterval is how often pending transcriptions are checked.
+	PollInterval time.Duration
+	// PollConcurrency is how many status calls the poller makes at once.
+	PollConcurrency int
+	// ProviderHTTPTimeout bounds every request to the provider, retries included.
+	ProviderHTTPTimeout time.Duration
+	// TranscriptionTimeout is the overall deadline of a transcription, from submission to storage.
+	TranscriptionTimeout time.Duration
+	// RejectNoSpeech stores a transcription whose utterances are all empty
+	// as failed instead of as an empty result.
+	RejectNoSpeech bool
+	// RetryEmptyUtterances re-fetches once when a completed transcript has no utterances.
+	RetryEmptyUtterances bool
+	// ExportCache keeps rendered exports in memory until the transcription changes.
+	ExportCache bool
+	// CompactAfter is the age after which a transcription's cached exports are dropped; zero keeps them.
+	CompactAfter time.Duration
+	// CompactInterval is how often the export cache is compacted.
+	CompactInterval time.Duration
+	// MaxAudioBytes is the largest accepted upload; bigger ones are rejected.
+	MaxAudioBytes int
+	// AudioFormatCheck rejects uploads whose first bytes match no known audio container.
+	AudioFormatCheck bool
+	// AudioMemoryBytes is the largest upload submitted straight from memory.
+	// Bigger uploads are spilled to a temporary file first.
+	AudioMemoryBytes int
+	// TempDir is where large uploads are spilled; empty means the system temp directory.
+	TempDir string
+	// TempFileMaxAge is the age after which a leftover temporary audio file is removed.
+	TempFileMaxAge time.Duration
+	// TempSweepInterval is how often leftover temporary audio files are looked for.
+	TempSweepInterval time.Duration
+	// RouteShortSeconds routes uploads shorter than this to ShortSpeechModel and
+	// the others to LongSpeechModel; zero disables routing.
+	RouteShortSeconds float64
+	// ShortSpeechModel is the AssemblyAI speech model for short uploads.
+	ShortSpeechModel string
+	// LongSpeechModel is the AssemblyAI speech model for long uploads.
+	LongSpeechModel string
+	// MaxUtteranceSeconds splits longer utterances at sentence boundaries; zero disables splitting.
+	MaxUtteranceSeconds float64
+	// NormalizeNumbers rewrites spelled-out numbers and dates as digits after transcription.
+	NormalizeNumbers bool
+	// ReadingWPM is the words per minute of the reading time estimate.
+	ReadingWPM float64
+	// LocalChapters derives chapters from pauses when provider chapters were not requested.
+	LocalChapters bool
+	// LocalChapterGapSeconds is the silence that starts a new local chapter.
+	LocalChapterGapSeconds float64
+	// TranslationTimeout bounds translating one transcript with translate_to or ?lang.
+	TranslationTimeout time.Duration
+	// SectionSummaries allows ?summaries=true, one-line LLM summaries of chapters and segments.
+	SectionSummaries bool
+	// SectionSummaryTimeout bounds the LLM calls of one request for section summaries.
+	SectionSummaryTimeout time.Duration
+	// MaxSummarySections caps the sections with text summarized by one request.
+	MaxSummarySections int
+	// SummaryFallback serves an extractive summary when no provider summary is available.
+	SummaryFallback bool
+	// MaxInlineUtterances caps the utterances returned as JSON by the GET endpoint; zero means no cap.
+	MaxInlineUtterances int
+	// MaxResponseBytes caps the JSON utterance list of the GET endpoint, truncating longer ones; zero means no cap.
+	MaxResponseBytes int
+	// DefaultResponseFormat is the GET transcription format used when the request names none.
+	DefaultResponseFormat string
+	// RateLimitRetries is how often a request rejected with 429 is retried.
+	RateLimitRetries int
+	// RateLimitBackoff is the first wait between 429 retries when the provider sends
+	// no Retry-After header; it doubles with every retry.
+	RateLimitBackoff time.Duration
+	// ProviderRetryAttempts is how often a provider request failing with a network
+	// error or a 5xx status is attempted in total.
+	ProviderRetryAttempts int
+	// ProviderRetryBase is the first wait between those attempts; it doubles with every retry.
+	ProviderRetryBase time.Duration
+	// WebhookSecret signs webhook payloads with HMAC-SHA256; empty sends them unsigned.
+	WebhookSecret string
+	// WebhookAttempts is how often a webhook delivery is tried in total.
+	WebhookAttempts int
+	// SubmitRatePerMinute limits the transcriptions each client may submit per minute; zero means no limit.
+	SubmitRatePerMinute int
+	// TranscriptionWorkers caps the transcriptions running in the background; 0 means no limit.
+	TranscriptionWorkers int
+	// SubmitRateBurst is how many submissions a client may make at once within that limit.
+	SubmitRateBurst int
+	// RequestRetryBudget caps the retries of all provider calls made for one transcription; zero means no cap.
+	RequestRetryBudget int
+	// SCCFrameRate is the frame rate of SCC caption timecodes.
+	SCCFrameRate float64
+	// SpeakerRoles are the roles ?roles= gives speakers in rank order; the last one is shared by the rest.
+	SpeakerRoles []string
+	// MetricsPublic serves /metrics without the service API key, for scrapers that cannot send it.
+	MetricsPublic bool
+	// AllowedOrigins are the cross-origin WebSocket origins accepted; "*" accepts all.
+	AllowedOrigins []string
+	// SpeakerAttributes collects provider speaker estimates such as gender and age.
+	// It is off by default for privacy.
+	SpeakerAttributes bool
+	// CreatedAtSource is createdAtServer or createdAtClient, the source of stored created_at timestamps.
+	CreatedAtSource string
+	// ClientTimestampMaxSkew bounds how far a client created_at may be from the server time.
+	ClientTimestampMaxSkew time.Duration
+	// Blocklist holds the words masked in stored transcripts, or is nil when BLOCKLIST_FILE is unset.
+	Blocklist map[string]bool
+	// Stopwords are the words left out of word frequency reports.
+	Stopwords map[string]bool
+}
+
+// Sources of stored created_at timestamps.
+const (
+	createdAtServer = "server"
+	createdAtClient = "client"
+)
+
+// cfg is the active configuration, populated by loadConfig.
+var cfg config
+
+// loadConfig reads the configuration from the environment.
+// It must be called after the .env file has been loaded.
+func loadConfig() {
+	cfg = config{
+		ListenAddr:             listenAddr(os.Getenv("HOST"), os.Getenv("PORT")),
+		ServiceAPIKey:          os.Getenv("SERVICE_API_KEY"),
+		AuthDisabled:           envBool("AUTH_DISABLED", false),
+		ShutdownGrace:          envDuration("SHUTDOWN_GRACE", 30*time.Second),
+		ReadinessProviderCheck: envBool("READINESS_PROVIDER_CHECK", false),
+		ReadinessProviderTTL:   envDuration("READINESS_PROVIDER_TTL", 30*time.Second),
+		StoreCompress:          envBool("STORE_COMPRESS", false),
+		StoreTTL:               envDuration("STORE_TTL", 0),
+		StoreSweepInterval:     envDuration("STORE_SWEEP_INTERVAL", 10*time.Minute),
+		LowConfidenceThreshold: envFloat("LOW_CONFIDENCE_THRESHOLD", 0.5),
+		PollInterval:           envDuration("POLL_INTERVAL", 3*time.Second),
+		TranscriptionTimeout:   envDuration("TRANSCRIPTION_TIMEOUT", 15*time.Minute),
+		PollConcurrency:        envInt("POLL_CONCURRENCY", 8),
+		ProviderHTTPTimeout:    envDuration("PROVIDER_HTTP_TIMEOUT", 10*time.Minute),
+		RejectNoSpeech:         envBool("REJECT_NO_SPEECH", false),
+		RetryEmptyUtterances:   envBool("RETRY_EMPTY_UTTERANCES", false),
+		ExportCache:            envBool("EXPORT_CACHE", false),
+		CompactAfter:           envDuration("COMPACT_AFTER", time.Hour),
+		CompactInterval:        envDuration("COMPACT_INTERVAL", 10*time.Minute),
+		MaxAudioBytes:          envInt("MAX_AUDIO_BYTES", 50<<20),
+		AudioFormatCheck:       envBool("AUDIO_FORMAT_CHECK", true),
+		AudioMemoryBytes:       envInt("AUDIO_MEMORY_BYTES", 8<<20),
+		TempDir:                os.Getenv("TEMP_DIR"),
+		TempFileMaxAge:         envDuration("TEMP_FILE_MAX_AGE", time.Hour),
+		TempSweepInterval:      envDuration("TEMP_SWEEP_INTERVAL", 10*time.Minute),
+		RouteShortSeconds:      envFloat("ROUTE_SHORT_SECONDS", 0),
+		ShortSpeechModel:       envString("SHORT_SPEECH_MODEL", "nano"),
+		LongSpeechModel:        envString("LONG_SPEECH_MODEL", "best"),
+		MaxUtteranceSeconds:    envFloat("MAX_UTTERANCE_SECONDS", 0),
+		NormalizeNumbers:       envBool("NORMALIZE_NUMBERS", false),
+		ReadingWPM:             envFloat("READING_WPM", 200),
+		SummaryFallback:        envBool("SUMMARY_FALLBACK", false),
+		SectionSummaries:       envBool("SECTION_SUMMARIES", false),
+		TranslationTimeout:     envDuration("TRANSLATION_TIMEOUT", 2*time.Minute),
+		SectionSummaryTimeout:  envDuration("SECTION_SUMMARY_TIMEOUT", 30*time.Second),
+		MaxSummarySections:     envInt("MAX_SUMMARY_SECTIONS", 50),
+		LocalChapters:          envBool("LOCAL_CHAPTERS", false),
+		LocalChapterGapSeconds: envFloat("LOCAL_CHAPTER_GAP_SECONDS", 10),
+		MaxInlineUtterances:    envInt("MAX_INLINE_UTTERANCES", 0),
+		MaxResponseBytes:       envInt("MAX_RESPONSE_BYTES", 0),
+		RateLimitRetries:       envInt("RATE_LIMIT_RETRIES", 3),
+		RateLimitBackoff:       envDuration("RATE_LIMIT_BACKOFF", time.Second),
+		ProviderRetryAttempts:  envInt("PROVIDER_RETRY_ATTEMPTS", 3),
+		ProviderRetryBase:      envDuration("PROVIDER_RETRY_BASE_DELAY", 500*time.Millisecond),
+		RequestRetryBudget:     envInt("REQUEST_RETRY_BUDGET", 0),
+		SubmitRatePerMinute:    envInt("SUBMIT_RATE_PER_MINUTE", 0),
+		SubmitRateBurst:        envInt("SUBMIT_RATE_BURST", 5),
+		TranscriptionWorkers:   envInt("TRANSCRIPTION_WORKERS", 32),
+		WebhookSecret:          os.Getenv("WEBHOOK_SECRET"),
+		WebhookAttempts:        envInt("WEBHOOK_ATTEMPTS", 3),
+		SCCFrameRate:           envFloat("SCC_FRAME_RATE", 29.97),
+		SpeakerAttributes:      envBool("SPEAKER_ATTRIBUTES", false),
+		MetricsPublic:          envBool("METRICS_PUBLIC", false),
+		ClientTimestampMaxSkew: envDuration("CLIENT_TIMESTAMP_MAX_SKEW", 5*time.Minute),
+	}
+	if cfg.PollInterval <= 0 {
+		warnInvalid("POLL_INTERVAL", cfg.PollInterval, "3s")
+		cfg.PollInterval = 3 * time.Second
+	}
+	if cfg.MaxSummarySections < 1 {
+		warnInvalid("MAX_SUMMARY_SECTIONS", cfg.MaxSummarySections, 50)
+		cfg.MaxSummarySections = 50
+	}
+	if cfg.PollConcurrency < 1 {
+		warnInvalid("POLL_CONCURRENCY", cfg.PollConcurrency, 8)
+		cfg.PollConcurrency = 8
+	}
+	if cfg.ProviderHTTPTimeout <= 0 {
+		warnInvalid("PROVIDER_HTTP_TIMEOUT", cfg.ProviderHTTPTimeout, "10m")
+		cfg.ProviderHTTPTimeout = 10 * time.Minute
+	}
+	if cfg.TranscriptionTimeout <= 0 {
+		warnInvalid("TRANSCRIPTION_TIMEOUT", cfg.TranscriptionTimeout, "15m")
+		cfg.TranscriptionTimeout = 15 * time.Minute
+	}
+	if cfg.ProviderRetryBase <= 0 {
+		warnInvalid("PROVIDER_RETRY_BASE_DELAY", cfg.ProviderRetryBase, "500ms")
+		cfg.ProviderRetryBase = 500 * time.Millisecond
+	}
+	if cfg.SubmitRateBurst < 1 {
+		warnInvalid("SUBMIT_RATE_BURST", cfg.SubmitRateBurst, 5)
+		cfg.SubmitRateBurst = 5
+	}
+	if cfg.WebhookAttempts < 1 {
+		warnInvalid("WEBHOOK_ATTEMPTS", cfg.WebhookAttempts, 3)
+		cfg.WebhookAttempts = 3
+	}
+	if cfg.SCCFrameRate < 1 {
+		warnInvalid("SCC_FRAME_RATE", cfg.SCCFrameRate, 29.97)
+		cfg.SCCFrameRate = 29.97
+	}
+
+	if cfg.ReadingWPM <= 0 {
+		warnInvalid("READING_WPM", cfg.ReadingWPM, 200)
+		cfg.ReadingWPM = 200
+	}
+	if cfg.StoreSweepInterval <= 0 {
+		warnInvalid("STORE_SWEEP_INTERVAL", cfg.StoreSweepInterval, "10m")
+		cfg.StoreSweepInterval = 10 * time.Minute
+	}
+	if cfg.CompactInterval <= 0 {
+		warnInvalid("COMPACT_INTERVAL", cfg.CompactInterval, "10m")
+		cfg.CompactInterval = 10 * time.Minute
+	}
+	if cfg.TempSweepInterval <= 0 {
+		warnInvalid("TEMP_SWEEP_INTERVAL", cfg.TempSweepInterval, "10m")
+		cfg.TempSweepInterval = 10 * time.Minute
+	}
+
+	switch cfg.CreatedAtSource = os.Getenv("CREATED_AT_SOURCE"); cfg.CreatedAtSource {
+	case createdAtServer, createdAtClient:
+	case "":
+		cfg.CreatedAtSource = createdAtServer
+	default:
+		warnInvalid("CREATED_AT_SOURCE", cfg.CreatedAtSource, createdAtServer)
+		cfg.CreatedAtSource = createdAtServer
+	}
+	if cfg.ClientTimestampMaxSkew <= 0 {
+		warnInvalid("CLIENT_TIMESTAMP_MAX_SKEW", cfg.ClientTimestampMaxSkew, "5m")
+		cfg.ClientTimestampMaxSkew = 5 * time.Minute
+	}
+
+	switch cfg.DefaultResponseFormat = os.Getenv("DEFAULT_RESPONSE_FORMAT"); cfg.DefaultResponseFormat {
+	case "json", "revai", "vtt":
+	case "":
+		cfg.DefaultResponseFormat = "json"
+	default:
+		warnInvalid("DEFAULT_RESPONSE_FORMAT", cfg.DefaultResponseFormat, "json")
+		cfg.DefaultResponseFormat = "json"
+	}
+
+	for _, origin := range strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			cfg.AllowedOrigins = append(cfg.AllowedOrigins, origin)
+		}
+	}
+
+	for _, role := range strings.Split(envString("SPEAKER_ROLES", "Host,Guest"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			cfg.SpeakerRoles = append(cfg.SpeakerRoles, role)
+		}
+	}
+
+	if path := os.Getenv("BLOCKLIST_FILE"); path != "" {
+		blocklist, err := loadBlocklist(path)
+		if err != nil {
+			slog.Warn("Failed to load blocklist, masking disabled", "error", err)
+		}
+		cfg.Blocklist = blocklist
+	}
+
+	stopwords, err := loadStopwords(os.Getenv("STOPWORDS_FILE"))
+	if err != nil {
+		slog.Warn("Failed to load stopwords, using defaults", "error", err)
+		stopwords, _ = loadStopwords("")
+	}
+	cfg.Stopwords = stopwords
+}
+
+// warnInvalid logs that the setting key has the invalid value v and def is used instead.
+func warnInvalid(key string, v, def any) {
+	if d, ok := v.(time.Duration); ok {
+		v = d.String()
+	}
+	slog.Warn("Invalid setting, using default", "key", key, "value", v, "default", def)
+}
+
+// listenAddr builds the bind address from HOST and PORT.
+// PORT may be given as "8080" or ":8080" and defaults to 8080;
+// an empty host binds to all interfaces.
+func listenAddr(host, port string) string {
+	port = strings.TrimPrefix(strings.TrimSpace(port), ":")
+	if port == "" {
+		port = "8080"
+	}
+	return net.JoinHostPort(strings.TrimSpace(host), port)
+}
+
+// envString reads a string from the environment variable key.
+// It returns def when the variable is unset.
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBool reads a boolean from the environment variable key.
+// It returns def when the variable is unset or cannot be parsed.
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		warnInvalid(key, v, def)
+		return def
+	}
+	return b
+}
+
+// envDuration reads a duration such as "30s" from the environment variable key.
+// It returns def when the variable is unset or cannot be parsed.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		warnInvalid(key, v, def)
+		return def
+	}
+	return d
+}
+
+// envFloat reads a floating point number from the environment variable key.
+// It returns def when the variable is unset or cannot be parsed.
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		warnInvalid(key, v, def)
+		return def
+	}
+	return f
+}
+
+// envInt reads an integer from the environment variable key.
+// It returns def when the variable is unset or cannot be parsed.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		warnInvalid(key, v, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// formatVTTTimestamp formats a time in seconds as a WebVTT timestamp.
+// The result has the form HH:MM:SS.mmm.
+func formatVTTTimestamp(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	h := ms / 3600000
+	m := (ms % 3600000) / 60000
+	s := (ms % 60000) / 1000
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, ms%1000)
+}
+
+// vttEscaper escapes the characters WebVTT cue text may not contain literally.
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// buildWordCues builds the payload of a single VTT cue with per-word timing.
+// Every word is wrapped in a <c> tag, and each word after the cue start is
+// preceded by a timestamp tag so players can highlight it karaoke-style.
+// The words are escaped with vttEscaper.
+func buildWordCues(cueStart float64, words []CleanWord) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		if w.Start > cueStart {
+			parts[i] = fmt.Sprintf("<%s><c>%s</c>", formatVTTTimestamp(w.Start), vttEscaper.Replace(w.Text))
+		} else {
+			parts[i] = fmt.Sprintf("<c>%s</c>", vttEscaper.Replace(w.Text))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// renderVTT renders utterances as a WebVTT document with one cue per utterance.
+// When words is true, the cue text carries per-word timing tags.
+// &, <, and > in the text are escaped, so any transcript yields a valid document.
+func renderVTT(utterances []CleanUtterance, words bool) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i, u := range utterances {
+		text := vttEscaper.Replace(u.Text)
+		if words {
+			text = buildWordCues(u.Start, u.Words)
+		}
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n", i+1, formatVTTTimestamp(u.Start), formatVTTTimestamp(u.End), text)
+	}
+	return b.String()
+}
+
+// hasWordTimestamps reports whether every utterance carries word timings.
+func hasWordTimestamps(utterances []CleanUtterance) bool {
+	for _, u := range utterances {
+		if len(u.Words) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// handleGetVTT retrieves the transcription for a given connection ID as WebVTT.
+// With ?words=true, each cue contains per-word timing tags.
+// If the transcription is not found, it returns a 404 error.
+func handleGetVTT(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	gen := exports.generation(id)
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	words := r.URL.Query().Get("words") == "true"
+	if words && !hasWordTimestamps(data) {
+		http.Error(w, "Word timestamps not available", http.StatusUnprocessableEntity)
+		return
+	}
+
+	format := "vtt"
+	if words {
+		format = "vtt-words"
+	}
+	out := exports.get(id, format, gen, func() string { return renderVTT(data, words) })
+	writeExport(w, r, format, "text/vtt; charset=utf-8", out)
+}
+
+// formatSRTTimestamp formats a time in seconds as an SRT timestamp.
+// The result has the form HH:MM:SS,mmm.
+func formatSRTTimestamp(seconds float64) string {
+	return strings.Replace(formatVTTTimestamp(seconds), ".", ",", 1)
+}
+
+// renderSRT renders utterances as an SRT document with one numbered cue per utterance.
+func renderSRT(utterances []CleanUtterance) string {
+	var b strings.Builder
+	for i, u := range utterances {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n", i+1, formatSRTTimestamp(u.Start), formatSRTTimestamp(u.End), u.Text)
+	}
+	return b.String()
+}
+
+// exportETag returns the strong ETag of an export: the format followed by a hash
+// of the rendered content, so it changes whenever the transcription is reprocessed.
+func exportETag(format, body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return fmt.Sprintf(`"%s-%s"`, format, hex.EncodeToString(sum[:8]))
+}
+
+// etagMatches reports whether an If-None-Match header matches etag.
+// The header may list several tags, weak ones included, or be "*".
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExport writes a rendered export with its ETag, or 304 Not Modified
+// when the request's If-None-Match already names it.
+func writeExport(w http.ResponseWriter, r *http.Request, format, contentType, body string) {
+	etag := exportETag(format, body)
+	w.Header().Set("ETag", etag)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	fmt.Fprint(w, body)
+}
+
+// writeAttachment writes a subtitle export as a file download named after the transcription.
+func writeAttachment(w http.ResponseWriter, r *http.Request, id, ext, contentType, body string) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transcription-%s.%s"`, id, ext))
+	writeExport(w, r, ext, contentType, body)
+}
+
+// handleDownloadSRT serves the transcription as an SRT subtitle file.
+// If the transcription is not found, it returns a 404 error.
+func handleDownloadSRT(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	gen := exports.generation(id)
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	out := exports.get(id, "srt", gen, func() string { return renderSRT(data) })
+	writeAttachment(w, r, id, "srt", "application/x-subrip; charset=utf-8", out)
+}
+
+// handleDownloadVTT serves the transcription as a WebVTT subtitle file.
+// If the transcription is not found, it returns a 404 error.
+func handleDownloadVTT(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	gen := exports.generation(id)
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	out := exports.get(id, "vtt", gen, func() string { return renderVTT(data, false) })
+	writeAttachment(w, r, id, "vtt", "text/vtt; charset=utf-8", out)
+}
+
+// RevAIElement is a text or punctuation element of a Rev.ai monologue.
+// Timing and confidence are only set on text elements.
+type RevAIElement struct {
+	Type       string   `json:"type"`
+	Value      string   `json:"value"`
+	TS         *float64 `json:"ts,omitempty"`
+	EndTS      *float64 `json:"end_ts,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// RevAIMonologue is a run of elements spoken by one speaker.
+type RevAIMonologue struct {
+	Speaker  int            `json:"speaker"`
+	Elements []RevAIElement `json:"elements"`
+}
+
+// RevAITranscript is the top-level Rev.ai transcript JSON.
+type RevAITranscript struct {
+	Monologues []RevAIMonologue `json:"monologues"`
+}
+
+// splitTrailingPunct splits trailing punctuation off a word, e.g. "Satya," -> "Satya", ",".
+func splitTrailingPunct(word string) (string, string) {
+	i := len(word)
+	for i > 0 && strings.ContainsRune(".,?!;:", rune(word[i-1])) {
+		i--
+	}
+	return word[:i], word[i:]
+}
+
+// toRevAI maps utterances into the Rev.ai transcript schema.
+// The fields correspond as follows:
+//
+//   - each utterance becomes one monologue; Speaker labels map to integers
+//     in order of first appearance (A -> 0, B -> 1), unlabeled utterances use 0
+//   - each word becomes a "text" element: Text -> value, Start -> ts,
+//     End -> end_ts, Confidence -> confidence
+//   - trailing punctuation of a word becomes its own "punct" element,
+//     and words are separated by " " punct elements
+//
+// Utterances without word timings become a single text element covering the utterance.
+func toRevAI(utterances []CleanUtterance) RevAITranscript {
+	speakers := make(map[string]int)
+	for i, label := range speakerOrder(utterances) {
+		speakers[label] = i
+	}
+
+	out := RevAITranscript{Monologues: make([]RevAIMonologue, len(utterances))}
+	for i, u := range utterances {
+		var elements []RevAIElement
+		if len(u.Words) == 0 {
+			start, end, conf := u.Start, u.End, u.Confidence
+			elements = append(elements, RevAIElement{Type: "text", Value: u.Text, TS: &start, EndTS: &end, Confidence: &conf})
+		}
+		for j, w := range u.Words {
+			start, end, conf := w.Start, w.End, w.Confidence
+			text, punct := splitTrailingPunct(w.Text)
+			elements = append(elements, RevAIElement{Type: "text", Value: text, TS: &start, EndTS: &end, Confidence: &conf})
+			if punct != "" {
+				elements = append(elements, RevAIElement{Type: "punct", Value: punct})
+			}
+			if j < len(u.Words)-1 {
+				elements = append(elements, RevAIElement{Type: "punct", Value: " "})
+			}
+		}
+		out.Monologues[i] = RevAIMonologue{Speaker: speakers[u.Speaker], Elements: elements}
+	}
+	return out
+}
+
+// formatInlineTimestamp formats a time in seconds as an [MM:SS] marker.
+// Minutes keep counting past an hour, e.g. [75:02].
+func formatInlineTimestamp(seconds float64) string {
+	total := int64(seconds)
+	return fmt.Sprintf("[%02d:%02d]", total/60, total%60)
+}
+
+// renderInline renders utterances as a single text blob with an inline
+// [MM:SS] marker at the start of each utterance, followed by the speaker
+// label when the utterance has one.
+func renderInline(utterances []CleanUtterance) string {
+	parts := make([]string, len(utterances))
+	for i, u := range utterances {
+		parts[i] = formatInlineTimestamp(u.Start) + " "
+		if u.Speaker != "" {
+			parts[i] += "Speaker " + u.Speaker + ": "
+		}
+		parts[i] += u.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+// handleGetInline retrieves the transcription as plain text with inline timestamps.
+// If the transcription is not found, it returns a 404 error.
+func handleGetInline(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	writeExport(w, r, "inline", "text/plain; charset=utf-8", renderInline(data))
+}
+
+// renderAudacityLabels renders utterances as an Audacity label track, one
+// "start<TAB>end<TAB>label" line per utterance with times in seconds.
+// Labels are prefixed with "Speaker X: " when the utterance has a speaker;
+// tabs and line breaks in the text are replaced by spaces so every label stays on its line.
+func renderAudacityLabels(utterances []CleanUtterance) string {
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	var b strings.Builder
+	for _, u := range utterances {
+		label := clean.Replace(u.Text)
+		if u.Speaker != "" {
+			label = "Speaker " + u.Speaker + ": " + label
+		}
+		fmt.Fprintf(&b, "%.6f\t%.6f\t%s\n", u.Start, u.End, label)
+	}
+	return b.String()
+}
+
+// handleGetAudacity retrieves the transcription as an Audacity label track.
+// If the transcription is not found, it returns a 404 error.
+func handleGetAudacity(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	gen := exports.generation(id)
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	out := exports.get(id, "audacity", gen, func() string { return renderAudacityLabels(data) })
+	writeExport(w, r, "audacity", "text/plain; charset=utf-8", out)
+}
+
+// formatClockTimestamp formats a time in seconds as an [HH:MM:SS] marker.
+func formatClockTimestamp(seconds float64) string {
+	total := int64(seconds)
+	return fmt.Sprintf("[%02d:%02d:%02d]", total/3600, total%3600/60, total%60)
+}
+
+// renderPlainText renders utterances as readable text, one line per utterance
+// such as "[00:01:23] Speaker A: ...". The timestamp and the speaker label can each be left out;
+// utterances without a speaker never get a label.
+func renderPlainText(utterances []CleanUtterance, timestamps, speakers bool) string {
+	var b strings.Builder
+	for _, u := range utterances {
+		if timestamps {
+			b.WriteString(formatClockTimestamp(u.Start) + " ")
+		}
+		if speakers && u.Speaker != "" {
+			b.WriteString("Speaker " + u.Speaker + ": ")
+		}
+		b.WriteString(u.Text + "\n")
+	}
+	return b.String()
+}
+
+// handleGetPlainText serves the transcription as plain text, one line per utterance.
+// ?timestamps=false and ?speakers=false leave out the timestamps and speaker labels.
+// If the transcription is not found, it returns a 404 error.
+func handleGetPlainText(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	timestamps := q.Get("timestamps") != "false"
+	speakers := q.Get("speakers") != "false"
+	writeExport(w, r, "txt", "text/plain; charset=utf-8", renderPlainText(data, timestamps, speakers))
+}
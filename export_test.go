@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestFormatVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:       "00:00:00.000",
+		1.5:     "00:00:01.500",
+		61.0004: "00:01:01.000",
+		59.9996: "00:01:00.000",
+		3723.25: "01:02:03.250",
+	}
+	for seconds, want := range tests {
+		if got := formatVTTTimestamp(seconds); got != want {
+			t.Errorf("formatVTTTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestBuildWordCues(t *testing.T) {
+	words := []CleanWord{
+		{Text: "Hello", Start: 1, End: 1.4},
+		{Text: "world", Start: 1.5, End: 2},
+	}
+	want := "<c>Hello</c> <00:00:01.500><c>world</c>"
+	if got := buildWordCues(1, words); got != want {
+		t.Errorf("buildWordCues = %q, want %q", got, want)
+	}
+}
+
+func TestRenderVTTWordCues(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "Hello world", Start: 1, End: 2, Words: []CleanWord{{Text: "Hello", Start: 1}, {Text: "world", Start: 1.5}}},
+		{Text: "Bye", Start: 3, End: 3.5, Words: []CleanWord{{Text: "Bye", Start: 3}}},
+	}
+
+	want := "WEBVTT\n" +
+		"\n1\n00:00:01.000 --> 00:00:02.000\n<c>Hello</c> <00:00:01.500><c>world</c>\n" +
+		"\n2\n00:00:03.000 --> 00:00:03.500\n<c>Bye</c>\n"
+	if got := renderVTT(utterances, true); got != want {
+		t.Errorf("renderVTT with words =\n%s\nwant\n%s", got, want)
+	}
+
+	plain := "WEBVTT\n" +
+		"\n1\n00:00:01.000 --> 00:00:02.000\nHello world\n" +
+		"\n2\n00:00:03.000 --> 00:00:03.500\nBye\n"
+	if got := renderVTT(utterances, false); got != plain {
+		t.Errorf("renderVTT =\n%s\nwant\n%s", got, plain)
+	}
+}
+
+func TestHandleGetVTTWordsRequireTimings(t *testing.T) {
+	storeTestTranscription(t, "vtt-1", []CleanUtterance{{Text: "no words", Start: 0, End: 1}})
+
+	if w := serveTranscription(handleGetVTT, "vtt-1", "words=true"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("words without timings: status = %d, want 422", w.Code)
+	}
+	w := serveTranscription(handleGetVTT, "vtt-1", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/vtt; charset=utf-8" {
+		t.Errorf("plain VTT: status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRenderVTTEscapesCueText(t *testing.T) {
+	utterances := []CleanUtterance{{
+		Text: "R&D <3 a>b", Start: 0, End: 1,
+		Words: []CleanWord{{Text: "R&D", Start: 0}, {Text: "<3", Start: 0.4}, {Text: "a>b", Start: 0.8}},
+	}}
+
+	plain := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\nR&amp;D &lt;3 a&gt;b\n"
+	if got := renderVTT(utterances, false); got != plain {
+		t.Errorf("renderVTT =\n%s\nwant\n%s", got, plain)
+	}
+	words := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\n" +
+		"<c>R&amp;D</c> <00:00:00.400><c>&lt;3</c> <00:00:00.800><c>a&gt;b</c>\n"
+	if got := renderVTT(utterances, true); got != words {
+		t.Errorf("renderVTT with words =\n%s\nwant\n%s", got, words)
+	}
+}
+
+func TestToRevAI(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "B", Text: "Hi, Ann.", Start: 0, End: 1, Words: []CleanWord{
+			{Text: "Hi,", Start: 0, End: 0.4, Confidence: 0.9},
+			{Text: "Ann.", Start: 0.5, End: 1, Confidence: 0.8},
+		}},
+		{Speaker: "A", Text: "Hello", Start: 1.5, End: 2, Confidence: 0.7},
+		{Speaker: "B", Text: "Ok", Start: 3, End: 3.5, Words: []CleanWord{{Text: "Ok", Start: 3, End: 3.5, Confidence: 1}}},
+	}
+
+	got, err := json.Marshal(toRevAI(utterances))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"monologues":[` +
+		`{"speaker":0,"elements":[` +
+		`{"type":"text","value":"Hi","ts":0,"end_ts":0.4,"confidence":0.9},` +
+		`{"type":"punct","value":","},` +
+		`{"type":"punct","value":" "},` +
+		`{"type":"text","value":"Ann","ts":0.5,"end_ts":1,"confidence":0.8},` +
+		`{"type":"punct","value":"."}]},` +
+		`{"speaker":1,"elements":[{"type":"text","value":"Hello","ts":1.5,"end_ts":2,"confidence":0.7}]},` +
+		`{"speaker":0,"elements":[{"type":"text","value":"Ok","ts":3,"end_ts":3.5,"confidence":1}]}]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestRenderInline(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "Welcome.", Start: 5.9},
+		{Text: "(music)", Start: 62},
+		{Speaker: "B", Text: "Thanks.", Start: 4502.5},
+	}
+
+	got := renderInline(utterances)
+	want := "[00:05] Speaker A: Welcome. [01:02] (music) [75:02] Speaker B: Thanks."
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	if got := renderInline(nil); got != "" {
+		t.Errorf("renderInline(nil) = %q, want empty", got)
+	}
+}
+
+func TestRenderAudacityLabels(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "Hello there", Start: 0, End: 1.5},
+		{Text: "line one\nline\ttwo\r\n", Start: 1.5, End: 3.25},
+		{Speaker: "B", Text: "Bye", Start: 3600.0625, End: 3601},
+	}
+
+	got := renderAudacityLabels(utterances)
+	want := "0.000000\t1.500000\tSpeaker A: Hello there\n" +
+		"1.500000\t3.250000\tline one line two  \n" +
+		"3600.062500\t3601.000000\tSpeaker B: Bye\n"
+	if got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+	if got := renderAudacityLabels(nil); got != "" {
+		t.Errorf("renderAudacityLabels(nil) = %q, want empty", got)
+	}
+}
+
+// serveExport calls handler for transcription id with the given If-None-Match header.
+func serveExport(handler http.HandlerFunc, id, ifNoneMatch string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/transcription/"+id+"/srt", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestWriteExportETag(t *testing.T) {
+	storeTestTranscription(t, "etag-1", []CleanUtterance{{Text: "hello", Start: 0, End: 1}})
+
+	first := serveExport(handleDownloadSRT, "etag-1", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Body.Len() == 0 {
+		t.Fatalf("first GET = %d, ETag %q, %d bytes, want 200 with an ETag and a body", first.Code, etag, first.Body.Len())
+	}
+
+	tests := []struct {
+		name, ifNoneMatch string
+		want              int
+	}{
+		{"same tag", etag, http.StatusNotModified},
+		{"weak tag", "W/" + etag, http.StatusNotModified},
+		{"one of several", `"srt-other", ` + etag, http.StatusNotModified},
+		{"any", "*", http.StatusNotModified},
+		{"other tag", `"srt-other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serveExport(handleDownloadSRT, "etag-1", tt.ifNoneMatch)
+		if w.Code != tt.want || w.Header().Get("ETag") != etag {
+			t.Errorf("%s: %d, ETag %q, want %d, ETag %q", tt.name, w.Code, w.Header().Get("ETag"), tt.want, etag)
+		}
+		if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%s: 304 with a %d byte body", tt.name, w.Body.Len())
+		}
+	}
+
+	// Reprocessing changes the content, so the old tag no longer matches.
+	saveTranscription("etag-1", transcriptEntry{Utterances: []CleanUtterance{{Text: "hello again", Start: 0, End: 1}}})
+	w := serveExport(handleDownloadSRT, "etag-1", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after reprocessing: %d, ETag %q, want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// providerPing checks whether the AssemblyAI API is reachable with the given key.
+// It lists at most one transcript, which is the cheapest authenticated call.
+// It is a variable so the readiness check can run against a fake provider.
+var providerPing = func(ctx context.Context, apiKey string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://api.assemblyai.com/v2/transcript?limit=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", apiKey)
+
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// providerHealth caches the result of the last provider ping.
+// A new ping is only issued once the cached result is older than the configured TTL,
+// so frequent readiness probes don't burn API quota.
+type providerHealth struct {
+	mu      sync.Mutex
+	clock   Clock
+	checked time.Time
+	err     error
+}
+
+// newProviderHealth creates a providerHealth that ages its cached result on clock.
+func newProviderHealth(clock Clock) *providerHealth {
+	return &providerHealth{clock: clock}
+}
+
+// check returns the cached provider status, pinging again if it has expired.
+func (p *providerHealth) check(ctx context.Context, apiKey string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.checked.IsZero() && p.clock.Now().Sub(p.checked) < cfg.ReadinessProviderTTL {
+		return p.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	p.err = providerPing(ctx, apiKey)
+	p.checked = p.clock.Now()
+	return p.err
+}
+
+// readinessHealth holds the cached provider status used by handleReadyz.
+// It is a variable so the readiness check can run on a fake clock.
+var readinessHealth = newProviderHealth(realClock{})
+
+// handleHealthz reports that the process is alive.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the service can accept transcriptions.
+// It requires the API key to be set and, when enabled, the provider to be reachable.
+// It returns 503 with the failing reason when the service is not ready.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	status := map[string]string{"status": "ready"}
+	code := http.StatusOK
+
+	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
+	if apiKey == "" {
+		status = map[string]string{"status": "not ready", "reason": "API key not configured"}
+		code = http.StatusServiceUnavailable
+	} else if cfg.ReadinessProviderCheck {
+		if err := readinessHealth.check(r.Context(), apiKey); err != nil {
+			status = map[string]string{"status": "not ready", "reason": "provider unreachable: " + err.Error()}
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleReadyzCachesProviderStatus(t *testing.T) {
+	t.Setenv("ASSEMBLYAI_API_KEY", "test-key")
+	useConfig(t, func(c *config) { c.ReadinessProviderCheck, c.ReadinessProviderTTL = true, 30*time.Second })
+	clock := newFakeClock(testEpoch)
+	prevHealth, prevPing := readinessHealth, providerPing
+	readinessHealth = newProviderHealth(clock)
+	t.Cleanup(func() { readinessHealth, providerPing = prevHealth, prevPing })
+
+	up, pings := true, 0
+	providerPing = func(ctx context.Context, apiKey string) error {
+		pings++
+		if !up {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	steps := []struct {
+		name      string
+		up        bool
+		advance   time.Duration
+		wantCode  int
+		wantPings int
+	}{
+		{"first probe pings", true, 0, http.StatusOK, 1},
+		{"outage hidden by the cache", false, 10 * time.Second, http.StatusOK, 1},
+		{"outage seen once the TTL passed", false, 20 * time.Second, http.StatusServiceUnavailable, 2},
+		{"recovery hidden by the cache", true, 5 * time.Second, http.StatusServiceUnavailable, 2},
+		{"recovery seen once the TTL passed", true, 30 * time.Second, http.StatusOK, 3},
+	}
+	for _, step := range steps {
+		up = step.up
+		clock.Advance(step.advance)
+		w := httptest.NewRecorder()
+		handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+		if w.Code != step.wantCode {
+			t.Errorf("%s: status = %d, want %d: %s", step.name, w.Code, step.wantCode, w.Body)
+		}
+		if pings != step.wantPings {
+			t.Errorf("%s: pings = %d, want %d", step.name, pings, step.wantPings)
+		}
+	}
+}
+
+func TestHandleReadyzRequiresAPIKey(t *testing.T) {
+	t.Setenv("ASSEMBLYAI_API_KEY", "")
+	w := httptest.NewRecorder()
+	handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// inFlight describes a transcription that is still being processed.
+type inFlight struct {
+	Status  string
+	Started time.Time
+	cancel  context.CancelFunc
+}
+
+// InFlightRegistry is the single source of truth for transcriptions in progress.
+// All methods are safe for concurrent use.
+type InFlightRegistry struct {
+	mu    sync.Mutex
+	jobs  map[string]inFlight
+	clock Clock
+}
+
+// inflight is the shared registry consulted by the status endpoints.
+var inflight = NewInFlightRegistry(realClock{})
+
+// NewInFlightRegistry creates an empty registry that stamps start times with clock.
+func NewInFlightRegistry(clock Clock) *InFlightRegistry {
+	return &InFlightRegistry{jobs: make(map[string]inFlight), clock: clock}
+}
+
+// Add registers a transcription with its initial status and cancel function.
+func (r *InFlightRegistry) Add(id, status string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id] = inFlight{Status: status, Started: r.clock.Now(), cancel: cancel}
+}
+
+// Update changes the status of a registered transcription.
+// It does nothing if the ID is not registered.
+func (r *InFlightRegistry) Update(id, status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; ok {
+		job.Status = status
+		r.jobs[id] = job
+	}
+}
+
+// Remove unregisters a transcription once it has finished.
+func (r *InFlightRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, id)
+}
+
+// Get returns the in-flight state of a transcription.
+// The bool reports whether the ID is registered.
+func (r *InFlightRegistry) Get(id string) (inFlight, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+// Cancel cancels a registered transcription, reporting whether it was found.
+// The entry is removed by its owner once it observes the cancellation.
+func (r *InFlightRegistry) Cancel(id string) bool {
+	r.mu.Lock()
+	job, ok := r.jobs[id]
+	r.mu.Unlock()
+	if ok && job.cancel != nil {
+		job.cancel()
+	}
+	return ok
+}
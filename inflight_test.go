@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestInFlightRegistryLifecycle(t *testing.T) {
+	r := NewInFlightRegistry(newFakeClock(testEpoch))
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Add("a", statusProcessing, cancel)
+
+	r.Update("a", "polling")
+	r.Update("missing", "polling")
+	if job, ok := r.Get("a"); !ok || job.Status != "polling" {
+		t.Errorf("Get after Update = %+v, %v", job, ok)
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Error("Update registered an unknown ID")
+	}
+
+	if !r.Cancel("a") {
+		t.Fatal("Cancel did not find the job")
+	}
+	if ctx.Err() == nil {
+		t.Error("Cancel did not cancel the job")
+	}
+	if _, ok := r.Get("a"); !ok {
+		t.Error("Cancel removed the entry; its owner should")
+	}
+	r.Remove("a")
+	if _, ok := r.Get("a"); ok || r.Cancel("a") {
+		t.Error("job still registered after Remove")
+	}
+}
+
+// TestInFlightRegistryConcurrent is meant to run with -race.
+func TestInFlightRegistryConcurrent(t *testing.T) {
+	r := NewInFlightRegistry(newFakeClock(testEpoch))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("job-%d", i%10)
+			_, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r.Add(id, statusProcessing, cancel)
+			r.Update(id, "polling")
+			r.Get(id)
+			r.Cancel(id)
+			r.Remove(id)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		if _, ok := r.Get(fmt.Sprintf("job-%d", i)); ok {
+			t.Errorf("job-%d still registered", i)
+		}
+	}
+}
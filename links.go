@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Patterns for the links detected in utterance text. Bare domains are only
+// recognized for common top-level domains, so sentence ends like "done.So" don't match.
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	urlPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|org|net|io|dev|ai|co|edu|gov|app)\b(?:/[^\s<>"]*)?`)
+)
+
+// Link is a URL or email address found in an utterance.
+// Start and End are character offsets into the utterance text, End exclusive.
+type Link struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// detectLinks finds the URLs and email addresses in text, ordered by position.
+// Trailing punctuation is not part of a link, and the domain of an email
+// address is not reported again as a URL.
+func detectLinks(text string) []Link {
+	type span struct {
+		kind       string
+		start, end int
+	}
+	var spans []span
+	for _, m := range emailPattern.FindAllStringIndex(text, -1) {
+		spans = append(spans, span{"email", m[0], m[1]})
+	}
+	for _, m := range urlPattern.FindAllStringIndex(text, -1) {
+		overlaps := false
+		for _, s := range spans {
+			if s.kind == "email" && m[0] < s.end && s.start < m[1] {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			spans = append(spans, span{"url", m[0], m[1]})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	links := []Link{}
+	for _, s := range spans {
+		value := strings.TrimRight(text[s.start:s.end], ".,;:!?)'\"")
+		start := utf8.RuneCountInString(text[:s.start])
+		links = append(links, Link{
+			Type:  s.kind,
+			Value: value,
+			Start: start,
+			End:   start + utf8.RuneCountInString(value),
+		})
+	}
+	return links
+}
+
+// UtteranceLinks lists the links of one utterance, identified by its index and timing.
+type UtteranceLinks struct {
+	Index int     `json:"index"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Links []Link  `json:"links"`
+}
+
+// linksByUtterance returns the links of every utterance that has any.
+func linksByUtterance(utterances []CleanUtterance) []UtteranceLinks {
+	out := []UtteranceLinks{}
+	for i, u := range utterances {
+		if links := detectLinks(u.Text); len(links) > 0 {
+			out = append(out, UtteranceLinks{Index: i, Start: u.Start, End: u.End, Links: links})
+		}
+	}
+	return out
+}
+
+// handleGetLinks returns the URLs and email addresses spoken in a transcription.
+// If the transcription is not found, it returns a 404 error.
+func handleGetLinks(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(linksByUtterance(data))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Link
+	}{
+		{"none", "nothing to see here", []Link{}},
+		{"url with scheme", "see https://example.com/docs.", []Link{{Type: "url", Value: "https://example.com/docs", Start: 4, End: 28}}},
+		{"www", "go to www.example.org", []Link{{Type: "url", Value: "www.example.org", Start: 6, End: 21}}},
+		{"bare domain", "it's on github.com/org/repo today", []Link{{Type: "url", Value: "github.com/org/repo", Start: 8, End: 27}}},
+		{"email not repeated as url", "mail jane.doe@example.com, thanks", []Link{{Type: "email", Value: "jane.doe@example.com", Start: 5, End: 25}}},
+		{"sentence end is not a domain", "we are done.So next", []Link{}},
+		{"ordered by position", "a@b.io or c.dev", []Link{
+			{Type: "email", Value: "a@b.io", Start: 0, End: 6},
+			{Type: "url", Value: "c.dev", Start: 10, End: 15},
+		}},
+		{"rune offsets", "café → example.com", []Link{{Type: "url", Value: "example.com", Start: 7, End: 18}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLinks(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectLinks(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinksByUtterance(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "hello", Start: 0, End: 1},
+		{Text: "visit example.com", Start: 1, End: 2},
+	}
+	got := linksByUtterance(utterances)
+	if len(got) != 1 || got[0].Index != 1 || got[0].Start != 1 || len(got[0].Links) != 1 {
+		t.Errorf("linksByUtterance = %+v, want the second utterance only", got)
+	}
+}
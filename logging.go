@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// parseLogLevel parses a LOG_LEVEL value: debug, info, warn, or error,
+// case-insensitively. An empty value means info; ok is false for anything else.
+func parseLogLevel(s string) (level slog.Level, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return slog.LevelInfo, false
+}
+
+// setupLogging makes a JSON slog handler at the given level the default logger.
+// Anything still written through the log package goes through it as well.
+func setupLogging(level string) {
+	lvl, ok := parseLogLevel(level)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})))
+	if !ok {
+		slog.Warn("Invalid setting, using default", "key", "LOG_LEVEL", "value", level, "default", "info")
+	}
+}
+
+// loggerKey is the context key of the request-scoped logger.
+type loggerKey struct{}
+
+// withLogger returns a copy of ctx carrying logger.
+func withLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFrom returns the logger carried by ctx, or the default logger.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// requestIDHeader carries the request ID, both from the client and in the response.
+const requestIDHeader = "X-Request-ID"
+
+// withRequestID tags every request with an ID, taken from X-Request-ID when the
+// client sends a reasonable one or generated otherwise. The ID is echoed in the
+// response and attached as request_id to the logger of the request context.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, id)
+		logger := slog.Default().With("request_id", id)
+		next.ServeHTTP(w, r.WithContext(withLogger(r.Context(), logger)))
+	})
+}
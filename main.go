@@ -3,12 +3,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
-	"sync"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/AssemblyAI/assemblyai-go-sdk"
@@ -16,211 +23,632 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// Word represents a single word inside an utterance of the transcript.
+// Start and end are in milliseconds, as returned by AssemblyAI.
+type Word struct {
+	Text       string  `json:"text"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+}
+
 // Utterance represents the structure of an utterance in the transcript.
-// It includes the text, speaker, start time, and end time.
+// It includes the text, speaker, start time, end time, confidence, and its words.
 type Utterance struct {
-	Text    string  `json:"text"`
-	Speaker string  `json:"speaker"`
-	Start   float64 `json:"start"`
-	End     float64 `json:"end"`
+	Text       string  `json:"text"`
+	Speaker    string  `json:"speaker"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+	Words      []Word  `json:"words"`
+}
+
+// CleanWord is a simplified version of Word for the final output.
+// Start and end are converted to seconds.
+type CleanWord struct {
+	Text       string  `json:"text"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
 }
 
 // CleanUtterance is a simplified version of Utterance for the final output.
-// It only includes the text, start time, and end time.
+// It includes the text, speaker, start time, end time, confidence, and the word timings.
+// OriginalText holds the provider's wording when post-processing changed Text.
 type CleanUtterance struct {
-	Text  string  `json:"text"`
-	Start float64 `json:"start"`
-	End   float64 `json:"end"`
+	Text         string  `json:"text"`
+	OriginalText string  `json:"original_text,omitempty"`
+	Speaker      string  `json:"speaker"`
+	Start        float64 `json:"start"`
+	End          float64 `json:"end"`
+	Confidence   float64 `json:"confidence"`
+	// LowConfidence is set when Confidence was below LOW_CONFIDENCE_THRESHOLD as the transcript was stored.
+	LowConfidence bool        `json:"low_confidence,omitempty"`
+	Words         []CleanWord `json:"words,omitempty"`
+	// Sentiment is only set when the upload requested sentiment=true.
+	Sentiment *Sentiment `json:"sentiment,omitempty"`
 }
 
-// Global map to store transcriptions keyed by connection ID.
-// This is used to retrieve transcriptions later.
-var (
-	transcriptions = make(map[string][]CleanUtterance)
-	mu             sync.Mutex
-)
-
-// getUtterancesFromTranscript fetches the utterances from a completed transcript using the AssemblyAI API.
-// It requires the API key and the transcript ID to make the request.
-// It returns a slice of Utterance or an error if the request fails.
-func getUtterancesFromTranscript(apiKey, transcriptID string) ([]Utterance, error) {
-	url := fmt.Sprintf("https://api.assemblyai.com/v2/transcript/%s", transcriptID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// ErrNoSpeech is returned when REJECT_NO_SPEECH is set and a transcription
+// has only empty utterances. The failure is stored under the connection ID.
+var ErrNoSpeech = errors.New("no speech detected in audio")
+
+// ErrTranscriptionTimeout is returned when a transcription does not complete
+// within TRANSCRIPTION_TIMEOUT.
+var ErrTranscriptionTimeout = errors.New("transcription timed out")
+
+// waitUntilCompleted waits until the transcription is completed or ctx ends.
+// It takes a context, a transcriber, and a transcript ID as parameters.
+// The status checks are batched with all other pending jobs by the shared poller,
+// leaving the full fetch to the caller.
+// onStatus, if not nil, is called with every status change, such as queued to processing.
+// It returns an error if polling fails or the transcription errored, and an error
+// wrapping ErrTranscriptionTimeout once the ctx deadline passes.
+func waitUntilCompleted(ctx context.Context, t Transcriber, transcriptID string, onStatus func(assemblyai.TranscriptStatus)) error {
+	err := jobPoller.wait(ctx, t, transcriptID, onStatus)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: no result after %v", ErrTranscriptionTimeout, cfg.TranscriptionTimeout)
 	}
-	req.Header.Set("Authorization", apiKey)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return err
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// fetchUtterances fetches the utterances of a completed transcription.
+// With RETRY_EMPTY_UTTERANCES enabled, an empty result is fetched once more after
+// the poll interval, as measured by clock, since a completed transcript occasionally
+// has no utterances yet. It only re-fetches and never resubmits, so the retry is not billed again.
+func fetchUtterances(ctx context.Context, clock Clock, t Transcriber, transcriptID string) ([]Utterance, error) {
+	utterances, err := t.Utterances(ctx, transcriptID)
+	if err != nil || len(utterances) > 0 || !cfg.RetryEmptyUtterances {
+		return utterances, err
 	}
 
-	var data struct {
-		Utterances []Utterance `json:"utterances"`
+	loggerFrom(ctx).Info("No utterances returned, retrying once", "transcript_id", transcriptID)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-clock.After(cfg.PollInterval):
 	}
-	if err := json.Unmarshal(bodyBytes, &data); err != nil {
-		log.Printf("Raw response: %s\n", string(bodyBytes))
-		return nil, err
-	}
-
-	return data.Utterances, nil
+	return t.Utterances(ctx, transcriptID)
 }
 
-// waitUntilCompleted polls the AssemblyAI API until the transcription is completed.
-// It takes a client and a transcript ID as parameters.
-// It returns the completed transcript or an error if the polling fails.
-func waitUntilCompleted(client *assemblyai.Client, transcriptID string) (assemblyai.Transcript, error) {
-	for {
-		tr, err := client.Transcripts.Get(context.Background(), transcriptID)
-		if err != nil {
-			return tr, err
-		}
-
-		log.Println("Transcript polling status:", tr.Status)
+// upgrader is used to upgrade HTTP connections to WebSocket connections.
+// Cross-origin requests must be allowed by ALLOWED_ORIGINS (see checkOrigin).
+var upgrader = websocket.Upgrader{
+	CheckOrigin: checkOrigin,
+}
 
-		switch tr.Status {
-		case assemblyai.TranscriptStatusCompleted:
-			return tr, nil
-		case assemblyai.TranscriptStatusError:
-			return tr, fmt.Errorf("transcription failed: %s", *tr.Error)
+// checkOrigin reports whether a WebSocket upgrade may proceed.
+// Requests without an Origin header and same-origin requests are always allowed.
+// Other origins must be in ALLOWED_ORIGINS, compared case-insensitively,
+// unless the list contains "*", which allows every origin.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range cfg.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
 		}
-
-		time.Sleep(3 * time.Second)
 	}
+	return false
 }
 
-// upgrader is used to upgrade HTTP connections to WebSocket connections.
-// It allows all origins for simplicity, but this should be restricted in production.
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+// hasSpeech reports whether any utterance contains non-whitespace text.
+func hasSpeech(utterances []CleanUtterance) bool {
+	for _, u := range utterances {
+		if strings.TrimSpace(u.Text) != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // handleWS handles incoming WebSocket connections.
-// It reads binary audio data from the WebSocket, saves it to a temporary file,
-// and sends it to AssemblyAI for transcription.
+// It reads binary audio data from the WebSocket, spilling large payloads
+// to a temporary file, and sends it to AssemblyAI for transcription.
+// By default, it replies with the connection ID as soon as the audio is accepted
+// and transcribes in the background, bounded by transcriptionWorkers; the result or
+// failure is then stored for the GET endpoint. With ?async=false, it instead keeps
+// the socket open until the transcription is done, sending progress frames and the result.
+// With ?mode=stream, the audio is instead streamed for real-time transcription (see handleStream).
+// Failures after the upgrade are reported with an error frame (see sendWSError)
+// before the socket is closed.
 func handleWS(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "batch" && mode != "stream" {
+		http.Error(w, "mode must be batch or stream", http.StatusBadRequest)
+		return
+	}
+	asyncParam := r.URL.Query().Get("async")
+	if asyncParam != "" && asyncParam != "true" && asyncParam != "false" {
+		http.Error(w, "async must be true or false", http.StatusBadRequest)
+		return
+	}
+	if asyncParam == "true" && mode == "stream" {
+		http.Error(w, "async is only supported in batch mode", http.StatusBadRequest)
+		return
+	}
+	async := asyncParam != "false"
+	params, err := transcriptParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseIngestOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("WebSocket upgrade failed:", err)
+		loggerFrom(r.Context()).Warn("WebSocket upgrade failed", "error", err)
 		return
 	}
 	defer conn.Close()
+	wsConns.add(conn)
+	defer wsConns.remove(conn)
 
 	connectionID := uuid.New().String()
-	log.Println("New connection:", connectionID)
-
-	mt, data, err := conn.ReadMessage()
-	if err != nil || mt != websocket.BinaryMessage {
-		log.Println("Failed to read binary audio:", err)
+	logger := loggerFrom(r.Context()).With("conn_id", connectionID)
+	logger.Info("New connection", "mode", mode, "cost_center", opts.CostCenter)
+
+	if !allowSubmission(r) {
+		logger.Warn("Submission rate limited", "client", clientIP(r))
+		sendWSError(conn, errCodeRateLimited, "too many transcriptions, try again later")
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "rate limited"), time.Now().Add(time.Second))
 		return
 	}
 
-	tmpfile, err := os.CreateTemp("", "*.wav")
-	if err != nil {
-		log.Println("Temp file creation failed:", err)
+	if mode == "stream" {
+		apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
+		if apiKey == "" {
+			logger.Error("API key not found in environment")
+			sendWSError(conn, errCodeNotConfigured, "transcription provider is not configured")
+			return
+		}
+		handleStream(withLogger(context.Background(), logger), conn, connectionID, apiKey)
 		return
 	}
-	defer os.Remove(tmpfile.Name())
 
-	if _, err := tmpfile.Write(data); err != nil {
-		log.Println("Failed to write to temp file:", err)
+	audio, err := readAudio(conn, cfg.MaxAudioBytes, cfg.AudioMemoryBytes)
+	if errors.Is(err, errAudioTooLarge) {
+		logger.Warn("Rejected oversized audio", "limit_bytes", cfg.MaxAudioBytes)
+		sendWSError(conn, errCodeAudioTooLarge, err.Error())
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseMessageTooBig, "audio too large"), time.Now().Add(time.Second))
+		return
+	}
+	if errors.Is(err, errAudioStorage) {
+		logger.Error("Storing audio failed", "error", err)
+		sendWSError(conn, errCodeAudioStorage, err.Error())
 		return
 	}
-	tmpfile.Close()
-
-	audioFile, err := os.Open(tmpfile.Name())
 	if err != nil {
-		log.Println("Open audio file failed:", err)
+		logger.Warn("Failed to read binary audio", "error", err)
+		sendWSError(conn, errCodeInvalidAudio, "expected binary audio chunks followed by a done message")
+		return
+	}
+
+	// The audio is closed on return, unless an async job takes it over.
+	cleanup := audio.close
+	defer func() { cleanup() }()
+
+	if format, ok := detectAudioFormat(audio.header()); ok {
+		logger.Info("Detected audio format", "format", format.Name)
+	} else if cfg.AudioFormatCheck {
+		logger.Warn("Rejected audio in an unrecognized format")
+		sendWSError(conn, errCodeUnsupportedFormat, "unsupported audio format: expected WAV, MP3, OGG, FLAC, or MP4/M4A")
 		return
 	}
-	defer audioFile.Close()
 
 	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
 	if apiKey == "" {
-		log.Println("API key not found in environment")
+		logger.Error("API key not found in environment")
+		sendWSError(conn, errCodeNotConfigured, "transcription provider is not configured")
 		return
 	}
-	client := assemblyai.NewClient(apiKey)
 
-	ctx := context.Background()
-	params := &assemblyai.TranscriptOptionalParams{
-		FormatText:    assemblyai.Bool(true),
-		Punctuate:     assemblyai.Bool(true),
-		SpeakerLabels: assemblyai.Bool(true),
+	release := func() {}
+	if async {
+		var ok bool
+		if release, ok = transcriptionWorkers.tryAcquire(); !ok {
+			logger.Warn("Rejected upload, all transcription workers busy")
+			sendWSError(conn, errCodeBusy, "server busy, try again later")
+			return
+		}
 	}
 
-	transcript, err := client.Transcripts.TranscribeFromReader(ctx, audioFile, params)
-	if err != nil {
-		log.Println("Transcription failed:", err)
+	transcriber := newUploadTranscriber(apiKey, audio)
+	logger.Info("Routed to provider", "provider", transcriber.Name(), "audio_bytes", audio.Len())
+
+	ctx, cancel := context.WithTimeout(withRetryBudget(withLogger(context.Background(), logger), cfg.RequestRetryBudget), cfg.TranscriptionTimeout)
+
+	if async {
+		inflight.Add(connectionID, statusProcessing, cancel)
+		closeAudio := cleanup
+		cleanup = func() {}
+		backgroundJobs.Go(func() {
+			defer release()
+			defer closeAudio()
+			defer cancel()
+			defer inflight.Remove(connectionID)
+			if _, _, err := transcribeUpload(ctx, transcriber, audio.open(), connectionID, params, opts, nil); err != nil {
+				saveFailure(connectionID, err.Error())
+			}
+		})
+		conn.WriteJSON(map[string]string{"connection_id": connectionID, "status": statusProcessing})
 		return
 	}
 
-	completedTranscript, err := waitUntilCompleted(client, *transcript.ID)
+	defer cancel()
+	inflight.Add(connectionID, statusProcessing, cancel)
+	defer inflight.Remove(connectionID)
+
+	// Only the status writer writes to conn until it is closed.
+	statuses := startStatusWriter(conn)
+	entry, code, err := transcribeUpload(ctx, transcriber, audio.open(), connectionID, params, opts, statuses.send)
+	statuses.close()
 	if err != nil {
-		log.Println("Polling failed:", err)
+		sendWSError(conn, code, err.Error())
 		return
 	}
 
-	utterances, err := getUtterancesFromTranscript(apiKey, *completedTranscript.ID)
+	result := map[string]string{"connection_id": connectionID, "language": entry.Language}
+	if entry.CostCenter != "" {
+		result["cost_center"] = entry.CostCenter
+	}
+	conn.WriteJSON(result)
+}
+
+// transcribeUpload submits uploaded audio and completes the transcription as
+// completeTranscription does, recording the metrics of the job.
+// It returns the WebSocket error code of the failing stage with the error.
+func transcribeUpload(ctx context.Context, t Transcriber, audio io.Reader, connectionID string, params *assemblyai.TranscriptOptionalParams, opts ingestOptions, onStatus func(assemblyai.TranscriptStatus)) (transcriptEntry, string, error) {
+	clock := jobPoller.clock
+	finishMetrics := startTranscriptionMetrics(clock)
+	transcriptID, err := t.Submit(ctx, audio, params)
 	if err != nil {
-		log.Println("Failed to get utterances:", err)
-		return
+		finishMetrics(time.Time{}, err)
+		loggerFrom(ctx).Error("Transcription submit failed", "error", err)
+		return transcriptEntry{}, errCodeSubmit, err
 	}
+	submitted := clock.Now()
+
+	entry, code, err := completeTranscription(ctx, t, transcriptID, connectionID, params, opts, onStatus)
+	finishMetrics(submitted, err)
+	return entry, code, err
+}
+
+// loadEntry looks up the stored entry named by the {id} route variable.
+// If it is not found, failed, or cannot be read, it writes an error response and returns false.
+func loadEntry(w http.ResponseWriter, r *http.Request) (transcriptEntry, bool) {
+	id := mux.Vars(r)["id"]
 
-	cleaned := make([]CleanUtterance, len(utterances))
-	for i, u := range utterances {
-		cleaned[i] = CleanUtterance{
-			Text:  u.Text,
-			Start: u.Start / 1000.0,
-			End:   u.End / 1000.0,
+	entry, ok, err := getTranscription(id)
+	if err != nil {
+		loggerFrom(r.Context()).Error("Failed to read stored transcription", "conn_id", id, "error", err)
+		http.Error(w, "Failed to read transcription", http.StatusInternalServerError)
+		return entry, false
+	}
+	if !ok {
+		if _, processing := inflight.Get(id); processing {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]string{"connection_id": id, "status": statusProcessing})
+			return entry, false
 		}
+		http.Error(w, "Transcription not found", http.StatusNotFound)
+		return entry, false
+	}
+	if entry.Status == statusFailed {
+		http.Error(w, "Transcription failed: "+entry.Error, http.StatusUnprocessableEntity)
+		return entry, false
 	}
+	return entry, true
+}
 
-	mu.Lock()
-	transcriptions[connectionID] = cleaned
-	mu.Unlock()
+// loadTranscription is loadEntry for handlers that only need the utterances.
+func loadTranscription(w http.ResponseWriter, r *http.Request) ([]CleanUtterance, bool) {
+	entry, ok := loadEntry(w, r)
+	return entry.Utterances, ok
+}
 
-	conn.WriteJSON(map[string]string{"connection_id": connectionID})
+// responseFormat picks the output format of the transcription GET endpoint.
+// An explicit ?format wins, then a specific Accept header,
+// then the DEFAULT_RESPONSE_FORMAT setting.
+func responseFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/vtt"):
+		return "vtt"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	}
+	return cfg.DefaultResponseFormat
 }
 
 // handleGetTranscription retrieves the transcription for a given connection ID.
-// It responds with the transcription data in JSON format.
+// It responds with the transcription data in JSON format, shaped by optional queries:
+//
+//   - lang=en returns the text translated into that language, see translatedTranscript
+//   - rebase=true shifts timings so the first utterance starts at 0
+//   - precision=N rounds timestamps to N decimal places (0-3)
+//   - contains=a,b keeps only utterances mentioning any keyword
+//   - order=desc returns the newest utterances first
+//   - fields=text,start returns only the selected utterance fields
+//   - units=both adds integer start_ms and end_ms next to the seconds
+//   - reading_time=true adds a reading_time_ms estimate at READING_WPM
+//   - words=true includes the per-word timings, which are left out by default
+//   - format=revai or format=vtt returns the Rev.ai schema or WebVTT instead
+//
 // If the transcription is not found, it returns a 404 error.
+// JSON responses with more than MAX_INLINE_UTTERANCES utterances are refused with
+// a 413 pointing at the export endpoints, which always return the full transcript.
+// The cost_center tag stored with the result, if any, is sent in X-Cost-Center.
 func handleGetTranscription(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return
+	}
+	data := entry.Utterances
+	if entry.CostCenter != "" {
+		w.Header().Set("X-Cost-Center", entry.CostCenter)
+	}
 
-	mu.Lock()
-	data, ok := transcriptions[id]
-	mu.Unlock()
+	if r.URL.Query().Has("lang") {
+		if data, ok = translatedTranscript(w, r, mux.Vars(r)["id"], entry); !ok {
+			return
+		}
+	}
 
-	if !ok {
-		http.Error(w, "Transcription not found", http.StatusNotFound)
+	if r.URL.Query().Get("rebase") == "true" {
+		data = rebase(data)
+	}
+
+	if p := r.URL.Query().Get("precision"); p != "" {
+		precision, err := strconv.Atoi(p)
+		if err != nil || precision < 0 || precision > 3 {
+			http.Error(w, "precision must be an integer between 0 and 3", http.StatusBadRequest)
+			return
+		}
+		data = withPrecision(data, precision)
+	}
+
+	if c := r.URL.Query().Get("contains"); r.URL.Query().Has("contains") {
+		kws, err := parseKeywords(c)
+		if err != nil {
+			http.Error(w, "contains must list at least one keyword", http.StatusBadRequest)
+			return
+		}
+		data = filterByKeywords(data, kws)
+	}
+
+	switch order := r.URL.Query().Get("order"); order {
+	case "", "asc":
+	case "desc":
+		data = sortByStart(data, true)
+	default:
+		http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	units := r.URL.Query().Get("units")
+	if units != "" && units != "s" && units != "both" {
+		http.Error(w, "units must be s or both", http.StatusBadRequest)
+		return
+	}
+	readingTime := r.URL.Query().Get("reading_time") == "true"
+
+	format := responseFormat(r)
+	if format != "vtt" && cfg.MaxInlineUtterances > 0 && len(data) > cfg.MaxInlineUtterances {
+		id := mux.Vars(r)["id"]
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":   fmt.Sprintf("transcription has %d utterances, more than the inline limit of %d", len(data), cfg.MaxInlineUtterances),
+			"exports": exportLinks(id),
+		})
+		return
+	}
+
+	switch format {
+	case "json":
+	case "revai":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toRevAI(data))
+		return
+	case "vtt":
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		fmt.Fprint(w, renderVTT(data, false))
+		return
+	default:
+		http.Error(w, "format must be json, revai, or vtt", http.StatusBadRequest)
+		return
+	}
+
+	if f := r.URL.Query().Get("fields"); f != "" {
+		fields, err := parseFields(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var extra []string
+		if units == "both" {
+			extra = append(extra, "start_ms", "end_ms")
+		}
+		if readingTime {
+			extra = append(extra, "reading_time_ms")
+		}
+		for _, f := range extra {
+			if !slices.Contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+		writeUtterancesJSON(w, r, data, func(u []CleanUtterance) any { return projectFields(u, fields) })
+		return
+	}
+
+	if r.URL.Query().Get("words") != "true" {
+		data = withoutWords(data)
+	}
+
+	if units == "both" || readingTime {
+		writeUtterancesJSON(w, r, data, func(u []CleanUtterance) any { return extendUtterances(u, units == "both", readingTime) })
+		return
+	}
+	writeUtterancesJSON(w, r, data, func(u []CleanUtterance) any { return u })
+}
+
+// exportLinks lists the export endpoints of a transcription, offered when it is too large to return inline.
+func exportLinks(id string) []string {
+	return []string{"/transcription/" + id + "/vtt", "/transcription/" + id + "/inline"}
+}
+
+// truncatedUtterances is the response sent instead of the utterance list when it
+// would exceed MAX_RESPONSE_BYTES. Utterances holds the leading ones that fit.
+type truncatedUtterances struct {
+	Utterances          any      `json:"utterances"`
+	TruncatedDueToSize  bool     `json:"truncated_due_to_size"`
+	UtteranceCount      int      `json:"utterance_count"`
+	TotalUtteranceCount int      `json:"total_utterance_count"`
+	Exports             []string `json:"exports"`
+}
+
+// writeUtterancesJSON writes render(data) as JSON. When MAX_RESPONSE_BYTES is set and the
+// body would exceed it, it writes a truncatedUtterances instead, with as many leading
+// utterances as fit, found by binary search, and links to the export endpoints.
+func writeUtterancesJSON(w http.ResponseWriter, r *http.Request, data []CleanUtterance, render func([]CleanUtterance) any) {
+	body, err := json.Marshal(render(data))
+	if err == nil && cfg.MaxResponseBytes > 0 && len(body) > cfg.MaxResponseBytes {
+		summary := truncatedUtterances{TruncatedDueToSize: true, TotalUtteranceCount: len(data), Exports: exportLinks(mux.Vars(r)["id"])}
+		encode := func(n int) ([]byte, error) {
+			summary.Utterances, summary.UtteranceCount = render(data[:n]), n
+			return json.Marshal(summary)
+		}
+		fits := sort.Search(len(data)+1, func(n int) bool {
+			b, err := encode(n)
+			return err != nil || len(b) > cfg.MaxResponseBytes
+		}) - 1
+		body, err = encode(max(fits, 0))
+	}
+	if err != nil {
+		loggerFrom(r.Context()).Error("Failed to encode transcription", "error", err)
+		http.Error(w, "Failed to encode transcription", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+	w.Write(append(body, '\n'))
 }
 
 func main() {
 	godotenv.Load()
+	setupLogging(os.Getenv("LOG_LEVEL"))
+	loadConfig()
+
+	providerHTTPClient.Timeout = cfg.ProviderHTTPTimeout
+	jobPoller = newPoller(cfg.PollInterval, cfg.PollConcurrency, realClock{})
+	go jobPoller.run(context.Background())
+	if cfg.StoreTTL > 0 {
+		go runStoreSweeper(context.Background(), cfg.StoreTTL, cfg.StoreSweepInterval, realClock{})
+	}
+	if cfg.ExportCache && cfg.CompactAfter > 0 {
+		go runCompactor(context.Background(), cfg.CompactAfter, cfg.CompactInterval, realClock{})
+	}
+	if cfg.SubmitRatePerMinute > 0 {
+		submitLimiter = newClientLimiter(cfg.SubmitRatePerMinute, cfg.SubmitRateBurst)
+		go runClientLimiterSweeper(context.Background(), submitLimiter, time.Minute, realClock{})
+	}
+	if cfg.TranscriptionWorkers > 0 {
+		transcriptionWorkers = newWorkerPool(cfg.TranscriptionWorkers)
+	}
+	go runTempSweeper(context.Background(), cfg.TempDir, cfg.TempFileMaxAge, cfg.TempSweepInterval, realClock{})
 
 	router := mux.NewRouter()
-	router.HandleFunc("/ws", handleWS)
-	router.HandleFunc("/transcription/{id}", handleGetTranscription).Methods("GET")
+	router.Use(withRequestID)
+	router.HandleFunc("/healthz", handleHealthz).Methods("GET")
+	router.HandleFunc("/readyz", handleReadyz).Methods("GET")
+	if cfg.MetricsPublic {
+		router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	}
+
+	// Everything but the health checks, and /metrics with METRICS_PUBLIC, requires the service API key.
+	api := router.NewRoute().Subrouter()
+	api.Use(requireAuth)
+	if !cfg.MetricsPublic {
+		api.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	}
+	api.HandleFunc("/ws", handleWS)
+	api.HandleFunc("/transcriptions", handleListTranscriptions).Methods("GET")
+	api.HandleFunc("/search", handleSearch).Methods("GET")
+	api.HandleFunc("/transcription/{id}.srt", handleDownloadSRT).Methods("GET")
+	api.HandleFunc("/transcription/{id}.vtt", handleDownloadVTT).Methods("GET")
+	api.HandleFunc("/transcription/{id}.txt", handleGetPlainText).Methods("GET")
+	api.HandleFunc("/transcription/{id}", handleGetTranscription).Methods("GET")
+	api.HandleFunc("/transcription/{id}", handleDeleteTranscription).Methods("DELETE")
+	api.HandleFunc("/transcription/{id}/vtt", handleGetVTT).Methods("GET")
+	api.HandleFunc("/transcription/{id}/inline", handleGetInline).Methods("GET")
+	api.HandleFunc("/transcription/{id}/scc", handleGetSCC).Methods("GET")
+	api.HandleFunc("/transcription/{id}/audacity", handleGetAudacity).Methods("GET")
+	api.HandleFunc("/transcription/{id}/gaps", handleGetGaps).Methods("GET")
+	api.HandleFunc("/transcription/{id}/meetings", handleGetMeetings).Methods("GET")
+	api.HandleFunc("/transcription/{id}/segments", handleGetSegments).Methods("GET")
+	api.HandleFunc("/transcription/{id}/sentences", handleGetSentences).Methods("GET")
+	api.HandleFunc("/transcription/{id}/words", handleGetWords).Methods("GET")
+	api.HandleFunc("/transcription/{id}/wordfreq", handleGetWordFrequencies).Methods("GET")
+	api.HandleFunc("/transcription/{id}/links", handleGetLinks).Methods("GET")
+	api.HandleFunc("/transcription/{id}/questions", handleGetQuestions).Methods("GET")
+	api.HandleFunc("/transcription/{id}/quality", handleGetQuality).Methods("GET")
+	api.HandleFunc("/transcription/{id}/review", handleGetReview).Methods("GET")
+	api.HandleFunc("/transcription/{id}/talktime", handleGetTalkTime).Methods("GET")
+	api.HandleFunc("/transcription/{id}/speakers", handleGetSpeakers).Methods("GET")
+	api.HandleFunc("/transcription/{id}/speakers", handleRenameSpeakers).Methods("PATCH")
+	api.HandleFunc("/transcription/{id}/engagement", handleGetEngagement).Methods("GET")
+	api.HandleFunc("/transcription/{id}/interactions", handleGetInteractions).Methods("GET")
+	api.HandleFunc("/transcription/{id}/abridged", handleGetAbridged).Methods("GET")
+	api.HandleFunc("/transcription/{id}/timeline", handleGetTimeline).Methods("GET")
+	api.HandleFunc("/transcription/{id}/karaoke", handleGetKaraoke).Methods("GET")
+	api.HandleFunc("/transcription/{id}/preview", handleGetPreview).Methods("GET")
+	api.HandleFunc("/transcription/{id}/topics", handleGetTopics).Methods("GET")
+	api.HandleFunc("/transcription/{id}/summary", handleGetSummary).Methods("GET")
+	api.HandleFunc("/transcription/{id}/chapters", handleGetChapters).Methods("GET")
+	api.HandleFunc("/transcription/{id}/chapters/{index}/utterances", handleGetChapterUtterances).Methods("GET")
+	api.HandleFunc("/transcription/{id}/cancel", handleCancel).Methods("POST")
+	api.HandleFunc("/transcribe", handleTranscribeURL).Methods("POST")
+	api.HandleFunc("/statuses", handleBulkStatus).Methods("POST")
+
+	if cfg.AuthDisabled {
+		slog.Warn("Authentication disabled by AUTH_DISABLED")
+	} else if cfg.ServiceAPIKey == "" {
+		slog.Warn("SERVICE_API_KEY not set, all authenticated requests will be rejected")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: router}
+	go func() {
+		slog.Info("Server running", "addr", cfg.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
 
-	port := ":8080"
-	fmt.Println("Server running on", port)
-	log.Fatal(http.ListenAndServe(port, router))
+	<-ctx.Done()
+	stop()
+	slog.Info("Received shutdown signal")
+	shutdown(srv, cfg.ShutdownGrace)
 }
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// useConfig applies set to cfg for the rest of the test, restoring the previous settings afterwards.
+func useConfig(t *testing.T, set func(c *config)) {
+	t.Helper()
+	prev := cfg
+	set(&cfg)
+	t.Cleanup(func() { cfg = prev })
+}
+
+// storeTestTranscription stores utterances under id for the rest of the test.
+func storeTestTranscription(t *testing.T, id string, utterances []CleanUtterance) {
+	t.Helper()
+	saveTranscription(id, transcriptEntry{Utterances: utterances})
+	t.Cleanup(func() { deleteTranscription(id) })
+}
+
+// serveTranscription calls handler for the transcription id with the given query string,
+// as the router would, and returns the recorded response.
+func serveTranscription(handler http.HandlerFunc, id, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/transcription/"+id+"?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// useFakeUploads makes WebSocket uploads go to ft for the rest of the test,
+// with the audio limits and timeout they need.
+func useFakeUploads(t *testing.T, ft Transcriber) {
+	t.Helper()
+	useTestPoller(t)
+	t.Setenv("ASSEMBLYAI_API_KEY", "test-key")
+	useConfig(t, func(c *config) {
+		c.MaxAudioBytes, c.AudioMemoryBytes = 1<<20, 1<<20
+		c.TranscriptionTimeout = time.Minute
+	})
+	prev := newUploadTranscriber
+	newUploadTranscriber = func(apiKey string, audio *uploadedAudio) Transcriber { return ft }
+	t.Cleanup(func() { newUploadTranscriber = prev })
+}
+
+// dialWS serves handleWS on a test server and connects to it with the given query string.
+func dialWS(t *testing.T, query string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(handleWS))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// uploadWS sends audio over conn as one binary message followed by "done".
+func uploadWS(t *testing.T, conn *websocket.Conn, audio []byte) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.BinaryMessage, audio); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(audioDoneMessage)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleWSTranscribesInBackgroundByDefault(t *testing.T) {
+	ft := &fakeTranscriber{utterances: [][]Utterance{{{Speaker: "A", Text: "hello", End: 1000}}}}
+	useFakeUploads(t, ft)
+	conn := dialWS(t, "")
+	uploadWS(t, conn, testWAV(32000, 320, 320))
+
+	var reply map[string]string
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	id := reply["connection_id"]
+	t.Cleanup(func() { deleteTranscription(id) })
+	if id == "" || reply["status"] != statusProcessing {
+		t.Fatalf("reply = %v, want a connection_id with status %q", reply, statusProcessing)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !backgroundJobs.wait(ctx) {
+		t.Fatal("background transcription did not finish")
+	}
+	if w := serveTranscription(handleGetTranscription, id, "format=json"); w.Code != http.StatusOK {
+		t.Errorf("GET status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestHandleWSWaitsWithAsyncFalse(t *testing.T) {
+	ft := &fakeTranscriber{
+		statuses:   []jobStatus{{Status: assemblyai.TranscriptStatusProcessing}, {Status: assemblyai.TranscriptStatusCompleted}},
+		utterances: [][]Utterance{{{Speaker: "A", Text: "hello", End: 1000}}},
+	}
+	useFakeUploads(t, ft)
+	conn := dialWS(t, "async=false")
+	uploadWS(t, conn, testWAV(32000, 320, 320))
+
+	var frames []map[string]string
+	for {
+		var frame map[string]string
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+		if frame["connection_id"] != "" || frame["error"] != "" {
+			break
+		}
+	}
+	result := frames[len(frames)-1]
+	id := result["connection_id"]
+	t.Cleanup(func() { deleteTranscription(id) })
+	if id == "" || result["language"] != defaultLanguage {
+		t.Fatalf("result = %v, want a connection_id with language %q", result, defaultLanguage)
+	}
+	if len(frames) < 2 || frames[0]["status"] != string(assemblyai.TranscriptStatusProcessing) {
+		t.Errorf("frames = %v, want progress frames before the result", frames)
+	}
+	if _, ok, _ := getTranscription(id); !ok {
+		t.Error("transcription not stored by the time the result was sent")
+	}
+}
+
+func TestHandleWSRejectsBadAsync(t *testing.T) {
+	for _, query := range []string{"async=maybe", "async=true&mode=stream"} {
+		r := httptest.NewRequest("GET", "/ws?"+query, nil)
+		w := httptest.NewRecorder()
+		handleWS(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHandleWSStoresNoSpeechFailureInBackground(t *testing.T) {
+	ft := &fakeTranscriber{utterances: [][]Utterance{{{Speaker: "A", Text: "  "}}}}
+	useFakeUploads(t, ft)
+	useConfig(t, func(c *config) { c.RejectNoSpeech = true })
+	conn := dialWS(t, "")
+	uploadWS(t, conn, testWAV(32000, 320, 320))
+
+	var reply map[string]string
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	id := reply["connection_id"]
+	t.Cleanup(func() { deleteTranscription(id) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !backgroundJobs.wait(ctx) {
+		t.Fatal("background transcription did not finish")
+	}
+
+	w := serveTranscription(handleGetTranscription, id, "format=json")
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), ErrNoSpeech.Error()) {
+		t.Errorf("GET = %d %q, want 422 with %q", w.Code, w.Body, ErrNoSpeech)
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		name, query, accept, def, want string
+	}{
+		{"query wins over accept", "format=revai", "text/vtt", "json", "revai"},
+		{"accept vtt", "", "text/vtt", "json", "vtt"},
+		{"accept json", "", "application/json, text/plain;q=0.5", "vtt", "json"},
+		{"wildcard accept uses default", "", "*/*", "vtt", "vtt"},
+		{"no accept uses default", "", "", "json", "json"},
+		{"unknown format passed through", "format=srt", "application/json", "json", "srt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *config) { c.DefaultResponseFormat = tt.def })
+			r := httptest.NewRequest("GET", "/transcription/x?"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := responseFormat(r); got != tt.want {
+				t.Errorf("got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleGetTranscriptionFormat(t *testing.T) {
+	useConfig(t, func(c *config) { c.DefaultResponseFormat = "" })
+	storeTestTranscription(t, "format-1", []CleanUtterance{{Speaker: "A", Text: "hi", End: 1}})
+
+	tests := []struct {
+		query, accept string
+		wantStatus    int
+		wantType      string
+	}{
+		{"", "text/vtt", http.StatusOK, "text/vtt; charset=utf-8"},
+		{"", "application/json", http.StatusOK, "application/json"},
+		{"format=json", "text/vtt", http.StatusOK, "application/json"},
+		{"format=revai", "", http.StatusOK, "application/json"},
+		{"format=srt", "", http.StatusBadRequest, "text/plain; charset=utf-8"},
+		{"", "", http.StatusBadRequest, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/transcription/format-1?"+tt.query, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": "format-1"})
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		handleGetTranscription(w, r)
+		if w.Code != tt.wantStatus || w.Header().Get("Content-Type") != tt.wantType {
+			t.Errorf("%q, Accept %q: %d %q, want %d %q", tt.query, tt.accept, w.Code, w.Header().Get("Content-Type"), tt.wantStatus, tt.wantType)
+		}
+	}
+}
+
+func TestHandleGetTranscriptionInlineLimit(t *testing.T) {
+	useConfig(t, func(c *config) { c.MaxInlineUtterances = 2 })
+	storeTestTranscription(t, "inline-at-cap", []CleanUtterance{{Text: "a"}, {Text: "b"}})
+	storeTestTranscription(t, "inline-over-cap", []CleanUtterance{{Text: "a"}, {Text: "b"}, {Text: "c"}})
+
+	if w := serveTranscription(handleGetTranscription, "inline-at-cap", "format=json"); w.Code != http.StatusOK {
+		t.Errorf("at cap: status = %d, want 200", w.Code)
+	}
+	if w := serveTranscription(handleGetTranscription, "inline-over-cap", "format=json&contains=a"); w.Code != http.StatusOK {
+		t.Errorf("filtered under cap: status = %d, want 200", w.Code)
+	}
+
+	w := serveTranscription(handleGetTranscription, "inline-over-cap", "format=json")
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("over cap: status = %d, want 413", w.Code)
+	}
+	var body struct {
+		Exports []string `json:"exports"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(body.Exports, exportLinks("inline-over-cap")) {
+		t.Errorf("exports = %v, want %v", body.Exports, exportLinks("inline-over-cap"))
+	}
+	if w := serveTranscription(handleGetTranscription, "inline-over-cap", "format=revai"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("revai over cap: status = %d, want 413", w.Code)
+	}
+
+	// WebVTT and the export endpoints still return every utterance.
+	if w := serveTranscription(handleGetTranscription, "inline-over-cap", "format=vtt"); w.Code != http.StatusOK {
+		t.Errorf("vtt over cap: status = %d, want 200", w.Code)
+	}
+	w = serveTranscription(handleGetInline, "inline-over-cap", "")
+	if w.Code != http.StatusOK || w.Body.String() != "[00:00] a [00:00] b [00:00] c" {
+		t.Errorf("inline export = %d %q, want all three utterances", w.Code, w.Body)
+	}
+}
+
+func TestHandleWSClosesOnOversizedAudio(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{"single frame", [][]byte{make([]byte, 1025)}},
+		{"chunks add up", [][]byte{make([]byte, 600), make([]byte, 600)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeTranscriber{}
+			useFakeUploads(t, ft)
+			useConfig(t, func(c *config) { c.MaxAudioBytes = 1024 })
+			conn := dialWS(t, "")
+			for _, chunk := range tt.chunks {
+				if err := conn.WriteMessage(websocket.BinaryMessage, chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var frame wsError
+			if err := conn.ReadJSON(&frame); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Code != errCodeAudioTooLarge || frame.Retryable {
+				t.Errorf("error frame = %+v, want non-retryable %s", frame, errCodeAudioTooLarge)
+			}
+			_, _, err := conn.ReadMessage()
+			if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+				t.Errorf("read after error = %v, want close %d", err, websocket.CloseMessageTooBig)
+			}
+			ft.mu.Lock()
+			defer ft.mu.Unlock()
+			if ft.submits != 0 {
+				t.Errorf("submitted %d transcriptions, want 0", ft.submits)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{"missing origin", nil, "", true},
+		{"same origin", nil, "http://api.example.com", true},
+		{"same origin other case", nil, "https://API.example.com", true},
+		{"listed origin", []string{"https://app.example.com"}, "https://app.example.com", true},
+		{"listed origin other case", []string{"https://App.Example.com"}, "https://app.example.com", true},
+		{"wildcard", []string{"*"}, "https://anywhere.test", true},
+		{"unlisted origin", []string{"https://app.example.com"}, "https://evil.test", false},
+		{"no list", nil, "https://app.example.com", false},
+		{"scheme must match", []string{"https://app.example.com"}, "http://app.example.com", false},
+		{"malformed origin", nil, "://bad", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *config) { c.AllowedOrigins = tt.allowed })
+			r := httptest.NewRequest("GET", "http://api.example.com/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := checkOrigin(r); got != tt.want {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleWSRejectsDisallowedOrigin(t *testing.T) {
+	useConfig(t, func(c *config) { c.AllowedOrigins = []string{"https://app.example.com"} })
+	srv := httptest.NewServer(http.HandlerFunc(handleWS))
+	t.Cleanup(srv.Close)
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", http.Header{"Origin": {"https://evil.test"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial from disallowed origin: err %v, resp %v, want 403", err, resp)
+	}
+}
+
+func TestWriteUtterancesJSONMaxResponseBytes(t *testing.T) {
+	data := make([]CleanUtterance, 5)
+	for i := range data {
+		data[i] = CleanUtterance{Text: strings.Repeat("word ", 40), Start: float64(i)}
+	}
+	render := func(u []CleanUtterance) any { return u }
+	full, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// truncatedSize is the size of the truncated response holding the first n utterances.
+	truncatedSize := func(n int) int {
+		b, err := json.Marshal(truncatedUtterances{
+			Utterances: data[:n], TruncatedDueToSize: true, UtteranceCount: n,
+			TotalUtteranceCount: len(data), Exports: exportLinks("limit-1"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(b)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int // utterances in a truncated response, or -1 for the full list
+	}{
+		{"no limit", 0, -1},
+		{"over the size", len(full) + 1, -1},
+		{"at the size", len(full), -1},
+		{"just under the size", len(full) - 1, len(data) - 1},
+		{"fits two", truncatedSize(2), 2},
+		{"just under two", truncatedSize(2) - 1, 1},
+		{"fits none", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *config) { c.MaxResponseBytes = tt.limit })
+			r := mux.SetURLVars(httptest.NewRequest("GET", "/transcription/limit-1", nil), map[string]string{"id": "limit-1"})
+			w := httptest.NewRecorder()
+			writeUtterancesJSON(w, r, data, render)
+
+			if tt.want < 0 {
+				if got := w.Body.String(); got != string(full)+"\n" {
+					t.Errorf("body = %s, want the full list", got)
+				}
+				return
+			}
+			var got struct {
+				Utterances          []CleanUtterance `json:"utterances"`
+				TruncatedDueToSize  bool             `json:"truncated_due_to_size"`
+				UtteranceCount      int              `json:"utterance_count"`
+				TotalUtteranceCount int              `json:"total_utterance_count"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.TruncatedDueToSize || got.UtteranceCount != tt.want || len(got.Utterances) != tt.want || got.TotalUtteranceCount != len(data) {
+				t.Errorf("got %+v, want %d of %d utterances, truncated", got, tt.want, len(data))
+			}
+		})
+	}
+}
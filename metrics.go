@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics of batch transcriptions, served at /metrics.
+var (
+	transcriptionsStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transcriptions_started_total",
+		Help: "Transcriptions submitted to the provider.",
+	})
+	transcriptionsCompleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transcriptions_completed_total",
+		Help: "Transcriptions that completed and were stored.",
+	})
+	transcriptionsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transcriptions_failed_total",
+		Help: "Transcriptions that failed at submission or afterwards.",
+	})
+	transcriptionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "transcription_duration_seconds",
+		Help:    "Time from submission to completion of successful transcriptions.",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	})
+	transcriptionsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "transcriptions_in_flight",
+		Help: "Transcriptions submitted and not yet completed or failed.",
+	})
+)
+
+// startTranscriptionMetrics counts a transcription as started and in flight.
+// The returned function records its outcome: pass the time it was submitted,
+// as read from clock, zero if submission failed, and the error it ended with.
+func startTranscriptionMetrics(clock Clock) func(submitted time.Time, err error) {
+	transcriptionsStarted.Inc()
+	transcriptionsInFlight.Inc()
+	return func(submitted time.Time, err error) {
+		transcriptionsInFlight.Dec()
+		if err != nil {
+			transcriptionsFailed.Inc()
+			return
+		}
+		transcriptionsCompleted.Inc()
+		transcriptionDuration.Observe(clock.Now().Sub(submitted).Seconds())
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// numberUnits maps the spelled-out numbers below twenty to their values.
+var numberUnits = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19,
+}
+
+// numberTens maps the spelled-out multiples of ten to their values.
+var numberTens = map[string]int{
+	"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+// numberScales maps the spelled-out scale words to their multipliers.
+var numberScales = map[string]int{
+	"hundred": 100, "thousand": 1000, "million": 1000000, "billion": 1000000000,
+}
+
+// dayOrdinals maps the spelled-out ordinals used for days of the month to their values.
+var dayOrdinals = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6,
+	"seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11,
+	"twelfth": 12, "thirteenth": 13, "fourteenth": 14, "fifteenth": 15,
+	"sixteenth": 16, "seventeenth": 17, "eighteenth": 18, "nineteenth": 19,
+	"twentieth": 20, "thirtieth": 30,
+}
+
+// monthNames lists the months after which a spelled-out ordinal is read as a day.
+var monthNames = map[string]bool{
+	"january": true, "february": true, "march": true, "april": true, "may": true, "june": true,
+	"july": true, "august": true, "september": true, "october": true, "november": true, "december": true,
+}
+
+// numberToken is a word of the text split from its surrounding punctuation.
+type numberToken struct {
+	lead, word, trail string
+}
+
+// splitToken separates leading and trailing punctuation from a word.
+func splitToken(s string) numberToken {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' }
+	start := strings.IndexFunc(s, isWordRune)
+	if start < 0 {
+		return numberToken{lead: s}
+	}
+	end := strings.LastIndexFunc(s, isWordRune) + 1
+	return numberToken{lead: s[:start], word: s[start:end], trail: s[end:]}
+}
+
+// twoDigitValue returns the value of a number word below one hundred,
+// including hyphenated forms such as "twenty-four".
+func twoDigitValue(word string) (int, bool) {
+	w := strings.ToLower(word)
+	if v, ok := numberUnits[w]; ok {
+		return v, true
+	}
+	if v, ok := numberTens[w]; ok {
+		return v, true
+	}
+	if tens, unit, found := strings.Cut(w, "-"); found {
+		t, ok1 := numberTens[tens]
+		u, ok2 := numberUnits[unit]
+		if ok1 && ok2 && u > 0 && u < 10 {
+			return t + u, true
+		}
+	}
+	return 0, false
+}
+
+// ordinalValue returns the day value of an ordinal word such as "third" or "twenty-first".
+func ordinalValue(word string) (int, bool) {
+	w := strings.ToLower(word)
+	if v, ok := dayOrdinals[w]; ok {
+		return v, true
+	}
+	if tens, unit, found := strings.Cut(w, "-"); found {
+		t, ok1 := numberTens[tens]
+		u, ok2 := dayOrdinals[unit]
+		if ok1 && ok2 && u < 10 {
+			return t + u, true
+		}
+	}
+	return 0, false
+}
+
+// yearValue reads two number words such as "twenty twenty-four" or
+// "nineteen eighty-four" as a year between 1000 and 2999.
+func yearValue(first, second string) (int, bool) {
+	a, ok1 := twoDigitValue(first)
+	b, ok2 := twoDigitValue(second)
+	if ok1 && ok2 && a >= 10 && a <= 29 && b >= 10 {
+		return a*100 + b, true
+	}
+	return 0, false
+}
+
+// numberParser accumulates the value of a run of number words.
+type numberParser struct {
+	total, current int
+	// last is the last word group added since the previous scale word, or -1.
+	last int
+}
+
+// take adds word to the number if it extends it, reporting whether it did.
+// For example "twenty" can be followed by "four" but not by "five" after "four".
+func (p *numberParser) take(word string) bool {
+	if scale, ok := numberScales[strings.ToLower(word)]; ok {
+		switch {
+		case scale == 100 && p.current > 0 && p.current < 100:
+			p.current *= 100
+		case scale > 100 && p.current > 0:
+			p.total += p.current * scale
+			p.current = 0
+		default:
+			return false
+		}
+		p.last = -1
+		return true
+	}
+
+	v, ok := twoDigitValue(word)
+	if !ok {
+		return false
+	}
+	switch {
+	case p.last == -1:
+	case p.last >= 20 && p.last%10 == 0 && v > 0 && v < 10:
+	default:
+		return false
+	}
+	p.current += v
+	p.last = v
+	return true
+}
+
+// value returns the number accumulated so far.
+func (p *numberParser) value() int {
+	return p.total + p.current
+}
+
+// normalizeNumbers rewrites spelled-out numbers and dates in text as digits.
+// For example "twenty twenty-four" becomes "2024", "three hundred and five"
+// becomes "305", and "March third" becomes "March 3". A lone "one" is left
+// alone, since it is far more often a pronoun ("no one") than a number.
+// Text without any number is returned unchanged.
+func normalizeNumbers(text string) string {
+	fields := strings.Fields(text)
+	tokens := make([]numberToken, len(fields))
+	for i, f := range fields {
+		tokens[i] = splitToken(f)
+	}
+
+	changed := false
+	out := make([]string, 0, len(fields))
+	for i := 0; i < len(tokens); {
+		t := tokens[i]
+
+		if i > 0 && monthNames[strings.ToLower(tokens[i-1].word)] && tokens[i-1].trail == "" {
+			if day, ok := ordinalValue(t.word); ok {
+				out = append(out, t.lead+strconv.Itoa(day)+t.trail)
+				changed = true
+				i++
+				continue
+			}
+		}
+
+		if i+1 < len(tokens) && t.trail == "" && tokens[i+1].lead == "" {
+			if year, ok := yearValue(t.word, tokens[i+1].word); ok {
+				out = append(out, t.lead+strconv.Itoa(year)+tokens[i+1].trail)
+				changed = true
+				i += 2
+				continue
+			}
+		}
+
+		p := numberParser{last: -1}
+		if !p.take(t.word) {
+			out = append(out, fields[i])
+			i++
+			continue
+		}
+
+		// Extend the run while the words keep forming one number,
+		// allowing "and" after hundred as in "three hundred and five".
+		j := i + 1
+		for j < len(tokens) && tokens[j-1].trail == "" && tokens[j].lead == "" {
+			w := tokens[j].word
+			if strings.EqualFold(w, "and") && strings.EqualFold(tokens[j-1].word, "hundred") &&
+				tokens[j].trail == "" && j+1 < len(tokens) && tokens[j+1].lead == "" && p.take(tokens[j+1].word) {
+				j += 2
+				continue
+			}
+			if !p.take(w) {
+				break
+			}
+			j++
+		}
+
+		if j == i+1 && strings.EqualFold(t.word, "one") {
+			out = append(out, fields[i])
+			i++
+			continue
+		}
+		out = append(out, t.lead+strconv.Itoa(p.value())+tokens[j-1].trail)
+		changed = true
+		i = j
+	}
+
+	if !changed {
+		return text
+	}
+	return strings.Join(out, " ")
+}
+
+// normalizeUtterances applies normalizeNumbers to every utterance,
+// keeping the original wording in OriginalText when the text changes.
+func normalizeUtterances(utterances []CleanUtterance) {
+	for i, u := range utterances {
+		if n := normalizeNumbers(u.Text); n != u.Text {
+			utterances[i].OriginalText = u.Text
+			utterances[i].Text = n
+		}
+	}
+}
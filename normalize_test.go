@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestNormalizeNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no numbers", "Let's get started.", "Let's get started."},
+		{"year", "Back in twenty twenty-four we shipped.", "Back in 2024 we shipped."},
+		{"hundred and", "We sold three hundred and five units", "We sold 305 units"},
+		{"thousands", "about two thousand five hundred users", "about 2500 users"},
+		{"hyphenated", "twenty-four hours", "24 hours"},
+		{"month ordinal", "See you March third.", "See you March 3."},
+		{"ordinal without month", "the third option", "the third option"},
+		{"lone one kept", "no one asked", "no one asked"},
+		{"one in a run", "one hundred percent", "100 percent"},
+		{"punctuation kept", "(seven), then eight.", "(7), then 8."},
+		{"run broken by punctuation", "five, six", "5, 6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeNumbers(tt.in); got != tt.want {
+				t.Errorf("normalizeNumbers(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeUtterancesKeepsOriginal(t *testing.T) {
+	utterances := []CleanUtterance{{Text: "ten minutes"}, {Text: "no change"}}
+	normalizeUtterances(utterances)
+
+	if utterances[0].Text != "10 minutes" || utterances[0].OriginalText != "ten minutes" {
+		t.Errorf("changed utterance = %+v", utterances[0])
+	}
+	if utterances[1].Text != "no change" || utterances[1].OriginalText != "" {
+		t.Errorf("unchanged utterance = %+v", utterances[1])
+	}
+}
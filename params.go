@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// transcriptParams builds the AssemblyAI request parameters for an upload.
+// Query options are validated here, before any audio is accepted.
+func transcriptParams(query url.Values) (*assemblyai.TranscriptOptionalParams, error) {
+	params := &assemblyai.TranscriptOptionalParams{
+		FormatText:    assemblyai.Bool(true),
+		Punctuate:     assemblyai.Bool(true),
+		SpeakerLabels: assemblyai.Bool(true),
+	}
+
+	if query.Get("topics") == "true" {
+		params.IABCategories = assemblyai.Bool(true)
+	}
+	if query.Get("chapters") == "true" {
+		params.AutoChapters = assemblyai.Bool(true)
+	}
+	if query.Get("filter_profanity") == "true" {
+		params.FilterProfanity = assemblyai.Bool(true)
+	}
+	if query.Get("sentiment") == "true" {
+		params.SentimentAnalysis = assemblyai.Bool(true)
+	}
+
+	switch lang := query.Get("language"); {
+	case lang == "":
+	case lang == "auto_detect":
+		params.LanguageDetection = assemblyai.Bool(true)
+	case supportedLanguages[lang]:
+		params.LanguageCode = assemblyai.TranscriptLanguageCode(lang)
+	default:
+		return nil, fmt.Errorf("unsupported language %q", lang)
+	}
+
+	if raw := query.Get("redact_pii"); raw != "" {
+		policies, err := parsePIIPolicies(raw)
+		if err != nil {
+			return nil, err
+		}
+		params.RedactPII = assemblyai.Bool(true)
+		params.RedactPIIPolicies = policies
+		params.RedactPIISub = assemblyai.SubstitutionPolicy("entity_name")
+	}
+
+	if err := applySummaryOptions(params, query.Get("summary_type"), query.Get("summary_model")); err != nil {
+		return nil, err
+	}
+
+	if raw := query.Get("provider_options"); raw != "" {
+		if err := applyProviderOptions(params, raw); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// summaryTypes and summaryModels list the accepted AssemblyAI summarization options.
+var (
+	summaryTypes  = []string{"bullets", "bullets_verbose", "gist", "headline", "paragraph"}
+	summaryModels = []string{"informative", "conversational", "catchy"}
+)
+
+// Defaults for summarization when only one of summary_type and summary_model is given.
+const (
+	defaultSummaryType  = "bullets"
+	defaultSummaryModel = "informative"
+)
+
+// applySummaryOptions enables AssemblyAI summarization when summaryType or
+// summaryModel is set, validating both and defaulting the one left empty.
+func applySummaryOptions(params *assemblyai.TranscriptOptionalParams, summaryType, summaryModel string) error {
+	if summaryType == "" && summaryModel == "" {
+		return nil
+	}
+	if summaryType == "" {
+		summaryType = defaultSummaryType
+	}
+	if summaryModel == "" {
+		summaryModel = defaultSummaryModel
+	}
+	if !slices.Contains(summaryTypes, summaryType) {
+		return fmt.Errorf("summary_type must be one of %s", strings.Join(summaryTypes, ", "))
+	}
+	if !slices.Contains(summaryModels, summaryModel) {
+		return fmt.Errorf("summary_model must be one of %s", strings.Join(summaryModels, ", "))
+	}
+
+	params.Summarization = assemblyai.Bool(true)
+	params.SummaryType = assemblyai.SummaryType(summaryType)
+	params.SummaryModel = assemblyai.SummaryModel(summaryModel)
+	return nil
+}
+
+// piiPolicies lists the AssemblyAI PII policies accepted by redact_pii.
+var piiPolicies = []string{
+	"account_number", "banking_information", "blood_type", "credit_card_cvv",
+	"credit_card_expiration", "credit_card_number", "date", "date_interval", "date_of_birth",
+	"drivers_license", "drug", "duration", "email_address", "event", "filename",
+	"gender_sexuality", "healthcare_number", "injury", "ip_address", "language", "location",
+	"marital_status", "medical_condition", "medical_process", "money_amount", "nationality",
+	"number_sequence", "occupation", "organization", "passport_number", "password",
+	"person_age", "person_name", "phone_number", "physical_attribute", "political_affiliation",
+	"religion", "statistics", "time", "url", "us_social_security_number", "username",
+	"vehicle_id", "zodiac_sign",
+}
+
+// parsePIIPolicies parses a comma-separated list of PII policies such as
+// "person_name,phone_number". Unknown policies are rejected.
+func parsePIIPolicies(raw string) ([]assemblyai.PIIPolicy, error) {
+	var policies []assemblyai.PIIPolicy
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !slices.Contains(piiPolicies, p) {
+			return nil, fmt.Errorf("unsupported redact_pii policy %q", p)
+		}
+		policies = append(policies, assemblyai.PIIPolicy(p))
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("redact_pii must list at least one policy")
+	}
+	return policies, nil
+}
+
+// defaultLanguage is the language AssemblyAI assumes when none is given.
+const defaultLanguage = "en_us"
+
+// supportedLanguages lists the language codes AssemblyAI accepts for pre-recorded audio.
+var supportedLanguages = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		en en_au en_uk en_us es fr de it pt nl hi ja zh fi ko pl ru tr uk vi
+		af am ar as az ba be bg bn bo br bs ca cs cy da el et eu fa fo gl gu ha haw he hr ht hu hy
+		id is jw ka kk km kn la lb ln lo lt lv mg mi mk ml mn mr ms mt my ne nn no oc pa ps ro
+		sa sd si sk sl sn so sq sr su sv sw ta te tg th tk tl tt ur uz yi yo`) {
+		supportedLanguages[code] = true
+	}
+}
+
+// allowedProviderOptions lists the AssemblyAI request fields clients may set
+// through provider_options. Anything that redirects results (webhooks) or
+// changes what the server stores and bills for is deliberately left out.
+var allowedProviderOptions = map[string]bool{
+	"audio_start_from":              true,
+	"audio_end_at":                  true,
+	"boost_param":                   true,
+	"custom_spelling":               true,
+	"disfluencies":                  true,
+	"language_confidence_threshold": true,
+	"punctuate":                     true,
+	"format_text":                   true,
+	"speakers_expected":             true,
+	"speech_model":                  true,
+	"speech_threshold":              true,
+	"word_boost":                    true,
+}
+
+// applyProviderOptions merges a raw provider_options JSON object into params.
+// It rejects keys outside allowedProviderOptions and values of the wrong type.
+func applyProviderOptions(params *assemblyai.TranscriptOptionalParams, raw string) error {
+	var opts map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &opts); err != nil {
+		return fmt.Errorf("provider_options must be a JSON object: %w", err)
+	}
+
+	var unknown []string
+	for k := range opts {
+		if !allowedProviderOptions[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("provider_options keys not allowed: %s", strings.Join(unknown, ", "))
+	}
+
+	base, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return err
+	}
+	for k, v := range opts {
+		merged[k] = v
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	var result assemblyai.TranscriptOptionalParams
+	if err := json.Unmarshal(out, &result); err != nil {
+		return fmt.Errorf("invalid provider_options value: %w", err)
+	}
+	*params = result
+	return nil
+}
+
+// costCenterPattern is the accepted format of a cost_center tag.
+var costCenterPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// parseCostCenter validates an optional cost_center tag.
+// An empty tag is allowed and means the request is not attributed.
+func parseCostCenter(tag string) (string, error) {
+	if tag != "" && !costCenterPattern.MatchString(tag) {
+		return "", fmt.Errorf("cost_center must be 1-64 letters, digits, '-' or '_'")
+	}
+	return tag, nil
+}
+
+// parseCallbackURL checks that a callback_url is an absolute http or https URL.
+func parseCallbackURL(raw string) (string, error) {
+	u, ok := parseAudioURL(raw)
+	if !ok {
+		return "", fmt.Errorf("callback_url must be an http or https URL")
+	}
+	return u, nil
+}
+
+// ingestOptions are the options of an upload handled by this server
+// rather than passed on to the provider.
+type ingestOptions struct {
+	// CostCenter is the chargeback tag stored with the result.
+	CostCenter string
+	// Summarize generates a LeMUR summary and action items after transcription.
+	Summarize bool
+	// CallbackURL receives the result of the transcription as a webhook; empty means none.
+	CallbackURL string
+	// CreatedAt is the client's creation timestamp, only read with CREATED_AT_SOURCE=client.
+	// Zero means the entry is stamped by the server.
+	CreatedAt time.Time
+	// TranslateTo is the language the transcript is translated into once done; empty means none.
+	TranslateTo string
+	// RoleHeuristic replaces speaker labels with the SPEAKER_ROLES ranked by it; empty means none.
+	RoleHeuristic string
+}
+
+// parseClientTimestamp validates an RFC 3339 created_at timestamp sent by a client.
+// It must be within maxSkew of now in either direction. An empty value returns the zero time.
+func parseClientTimestamp(v string, now time.Time, maxSkew time.Duration) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("created_at must be an RFC 3339 timestamp")
+	}
+	if d := t.Sub(now); d > maxSkew || d < -maxSkew {
+		return time.Time{}, fmt.Errorf("created_at must be within %s of the server time", maxSkew)
+	}
+	return t, nil
+}
+
+// parseIngestOptions reads the server-side upload options from the query.
+func parseIngestOptions(query url.Values) (ingestOptions, error) {
+	costCenter, err := parseCostCenter(query.Get("cost_center"))
+	if err != nil {
+		return ingestOptions{}, err
+	}
+	opts := ingestOptions{CostCenter: costCenter, Summarize: query.Get("summarize") == "true"}
+	if raw := query.Get("callback_url"); raw != "" {
+		if opts.CallbackURL, err = parseCallbackURL(raw); err != nil {
+			return ingestOptions{}, err
+		}
+	}
+	if opts.RoleHeuristic, err = parseRoleHeuristic(query.Get("roles")); err != nil {
+		return ingestOptions{}, err
+	}
+	if opts.TranslateTo, err = parseTranslationTarget(query.Get("translate_to")); err != nil {
+		return ingestOptions{}, fmt.Errorf("translate_to: %w", err)
+	}
+	if cfg.CreatedAtSource == createdAtClient {
+		opts.CreatedAt, err = parseClientTimestamp(query.Get("created_at"), storeClock.Now(), cfg.ClientTimestampMaxSkew)
+		if err != nil {
+			return ingestOptions{}, err
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+func TestTranscriptParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(*assemblyai.TranscriptOptionalParams) bool
+	}{
+		{"defaults", "", false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return *p.SpeakerLabels && *p.Punctuate && p.LanguageCode == "" && p.AutoChapters == nil
+		}},
+		{"language", "language=de", false, func(p *assemblyai.TranscriptOptionalParams) bool { return p.LanguageCode == "de" }},
+		{"auto detect", "language=auto_detect", false, func(p *assemblyai.TranscriptOptionalParams) bool { return *p.LanguageDetection }},
+		{"unsupported language", "language=xx", true, nil},
+		{"analyses", "topics=true&chapters=true&sentiment=true", false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return *p.IABCategories && *p.AutoChapters && *p.SentimentAnalysis
+		}},
+		{"pii", "redact_pii=person_name,%20phone_number", false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return *p.RedactPII && len(p.RedactPIIPolicies) == 2 && p.RedactPIIPolicies[1] == "phone_number"
+		}},
+		{"unknown pii policy", "redact_pii=shoe_size", true, nil},
+		{"empty pii list", "redact_pii=,", true, nil},
+		{"summary type defaults model", "summary_type=gist", false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return *p.Summarization && p.SummaryType == "gist" && p.SummaryModel == defaultSummaryModel
+		}},
+		{"summary model defaults type", "summary_model=catchy", false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return p.SummaryType == defaultSummaryType && p.SummaryModel == "catchy"
+		}},
+		{"invalid summary type", "summary_type=haiku", true, nil},
+		{"provider options", `provider_options={"speakers_expected":3,"punctuate":false}`, false, func(p *assemblyai.TranscriptOptionalParams) bool {
+			return *p.SpeakersExpected == 3 && !*p.Punctuate && *p.SpeakerLabels
+		}},
+		{"provider option not allowed", `provider_options={"webhook_url":"https://x.test"}`, true, nil},
+		{"provider option wrong type", `provider_options={"speakers_expected":"three"}`, true, nil},
+		{"provider options not an object", `provider_options=[1]`, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := transcriptParams(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(params) {
+				t.Errorf("unexpected params %+v", params)
+			}
+		})
+	}
+}
+
+func TestParseCostCenter(t *testing.T) {
+	tests := map[string]bool{
+		"":                       true,
+		"team-a_1":               true,
+		"has space":              false,
+		"semi;colon":             false,
+		string(make([]byte, 65)): false,
+	}
+	for tag, valid := range tests {
+		if _, err := parseCostCenter(tag); (err == nil) != valid {
+			t.Errorf("parseCostCenter(%q) err = %v, want valid %v", tag, err, valid)
+		}
+	}
+}
+
+func TestParseClientTimestamp(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v       string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2024-03-01T11:30:00Z", now.Add(-30 * time.Minute), false},
+		{"2024-03-01T13:30:00+01:00", now.Add(30 * time.Minute), false},
+		{"2024-03-01T14:00:00Z", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseClientTimestamp(tt.v, now, time.Hour)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseClientTimestamp(%q) = %v, %v, want %v, err %v", tt.v, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseIngestOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    ingestOptions
+		wantErr bool
+	}{
+		{"", ingestOptions{}, false},
+		{"cost_center=sales&summarize=true", ingestOptions{CostCenter: "sales", Summarize: true}, false},
+		{"callback_url=https://hooks.example.com/t", ingestOptions{CallbackURL: "https://hooks.example.com/t"}, false},
+		{"translate_to=PT-BR", ingestOptions{TranslateTo: "pt-br"}, false},
+		{"callback_url=ftp://example.com", ingestOptions{}, true},
+		{"cost_center=a%20b", ingestOptions{}, true},
+		{"translate_to=english", ingestOptions{}, true},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := parseIngestOptions(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// ErrTranscriptFailed is returned when the provider reports that a transcription errored.
+var ErrTranscriptFailed = errors.New("transcription failed")
+
+// pendingJob is a transcription waiting for completion in the poller.
+// The final result is delivered once on done.
+// onStatus, if set, is called from the poller whenever the status changes,
+// so it must not block or write to the client itself; see statusWriter.
+type pendingJob struct {
+	transcriber Transcriber
+	done        chan error
+	onStatus    func(assemblyai.TranscriptStatus)
+	last        assemblyai.TranscriptStatus
+	// ctx is the context of the waiting request, carrying its deadline,
+	// logger, and retry budget; status calls for the job are made under it.
+	ctx context.Context
+}
+
+// statusCallTimeout bounds a single status call, so a hung provider request
+// holds up its poll worker for at most this long, even before the job's deadline.
+const statusCallTimeout = 30 * time.Second
+
+// poller checks the status of all pending transcriptions from a single loop.
+// On every interval it polls each pending job once, up to concurrency at a time,
+// and dispatches the ones that finished, instead of running one polling goroutine per job.
+type poller struct {
+	interval    time.Duration
+	concurrency int
+	clock       Clock
+
+	mu   sync.Mutex
+	jobs map[string]*pendingJob
+}
+
+// jobPoller is the shared poller used by waitUntilCompleted, started in main.
+var jobPoller *poller
+
+// newPoller creates a poller that checks pending jobs every interval, as measured by clock,
+// with at most concurrency status calls in flight.
+func newPoller(interval time.Duration, concurrency int, clock Clock) *poller {
+	return &poller{
+		interval:    interval,
+		concurrency: max(concurrency, 1),
+		clock:       clock,
+		jobs:        make(map[string]*pendingJob),
+	}
+}
+
+// run polls the pending jobs until ctx is cancelled.
+func (p *poller) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.clock.After(p.interval):
+			p.pollOnce(ctx)
+		}
+	}
+}
+
+// pollOnce checks every pending job once, with up to p.concurrency status calls
+// at a time, and dispatches finished ones. It returns once every job was checked,
+// or ctx ends. The job list is copied first so status calls don't hold the lock.
+func (p *poller) pollOnce(ctx context.Context) {
+	p.mu.Lock()
+	batch := make(map[string]*pendingJob, len(p.jobs))
+	for id, job := range p.jobs {
+		batch[id] = job
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.concurrency)
+	for id, job := range batch {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(id string, job *pendingJob) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			p.pollJob(id, job)
+		}(id, job)
+	}
+	wg.Wait()
+}
+
+// pollJob checks the status of one job under its own context and dispatches it if finished.
+// A job whose context ended was already dropped by wait, so its error goes nowhere.
+func (p *poller) pollJob(id string, job *pendingJob) {
+	ctx, cancel := context.WithTimeout(job.ctx, statusCallTimeout)
+	defer cancel()
+	st, err := job.transcriber.Status(ctx, id)
+	if err != nil {
+		p.finish(id, err)
+		return
+	}
+
+	logger := loggerFrom(job.ctx)
+	logger.Debug("Transcript polling status", "transcript_id", id, "status", st.Status)
+	if st.Status != job.last {
+		logger.Info("Transcription status changed", "transcript_id", id, "status", st.Status)
+		job.last = st.Status
+		if job.onStatus != nil {
+			job.onStatus(st.Status)
+		}
+	}
+
+	switch st.Status {
+	case assemblyai.TranscriptStatusCompleted:
+		p.finish(id, nil)
+	case assemblyai.TranscriptStatusError:
+		p.finish(id, fmt.Errorf("%w: %s", ErrTranscriptFailed, st.Error))
+	}
+}
+
+// finish removes a job and delivers its result, if it is still pending.
+func (p *poller) finish(transcriptID string, err error) {
+	p.mu.Lock()
+	job, ok := p.jobs[transcriptID]
+	delete(p.jobs, transcriptID)
+	p.mu.Unlock()
+
+	if ok {
+		job.done <- err
+	}
+}
+
+// wait registers a transcription with the poller and blocks until it finishes.
+// If ctx ends first, the job is dropped and the context error is returned,
+// so each caller keeps its own timeout. onStatus may be nil.
+func (p *poller) wait(ctx context.Context, t Transcriber, transcriptID string, onStatus func(assemblyai.TranscriptStatus)) error {
+	job := &pendingJob{transcriber: t, done: make(chan error, 1), onStatus: onStatus, ctx: ctx}
+
+	p.mu.Lock()
+	p.jobs[transcriptID] = job
+	p.mu.Unlock()
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		p.mu.Lock()
+		delete(p.jobs, transcriptID)
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// addJob registers a job with p directly, without blocking as wait does.
+func addJob(p *poller, ctx context.Context, id string, t Transcriber) *pendingJob {
+	job := &pendingJob{transcriber: t, done: make(chan error, 1), ctx: ctx}
+	p.mu.Lock()
+	p.jobs[id] = job
+	p.mu.Unlock()
+	return job
+}
+
+func TestPollOnceDispatchesFinishedJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  jobStatus
+		done    bool
+		wantErr error
+	}{
+		{"completed", jobStatus{Status: assemblyai.TranscriptStatusCompleted}, true, nil},
+		{"errored", jobStatus{Status: assemblyai.TranscriptStatusError, Error: "bad audio"}, true, ErrTranscriptFailed},
+		{"processing", jobStatus{Status: assemblyai.TranscriptStatusProcessing}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPoller(time.Second, 1, newFakeClock(testEpoch))
+			var seen []assemblyai.TranscriptStatus
+			job := addJob(p, context.Background(), "t1", &fakeTranscriber{statuses: []jobStatus{tt.status}})
+			job.onStatus = func(st assemblyai.TranscriptStatus) { seen = append(seen, st) }
+
+			p.pollOnce(context.Background())
+
+			if len(seen) != 1 || seen[0] != tt.status.Status {
+				t.Errorf("onStatus saw %v, want [%s]", seen, tt.status.Status)
+			}
+			select {
+			case err := <-job.done:
+				if !tt.done {
+					t.Fatalf("job finished with %v, want still pending", err)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if tt.done {
+					t.Fatal("job still pending, want finished")
+				}
+				if len(p.jobs) != 1 {
+					t.Errorf("pending jobs = %d, want 1", len(p.jobs))
+				}
+			}
+		})
+	}
+}
+
+// blockingTranscriber is a fakeTranscriber whose status calls announce
+// themselves on entered and then block until release is closed.
+type blockingTranscriber struct {
+	fakeTranscriber
+	entered chan struct{}
+	release chan struct{}
+
+	mu          sync.Mutex
+	active, max int
+}
+
+func (b *blockingTranscriber) Status(ctx context.Context, transcriptID string) (jobStatus, error) {
+	b.mu.Lock()
+	b.active++
+	b.max = max(b.max, b.active)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.active--
+		b.mu.Unlock()
+	}()
+
+	b.entered <- struct{}{}
+	<-b.release
+	return jobStatus{Status: assemblyai.TranscriptStatusCompleted}, nil
+}
+
+func TestPollOnceBoundsConcurrentStatusCalls(t *testing.T) {
+	const jobs, concurrency = 5, 2
+	p := newPoller(time.Second, concurrency, newFakeClock(testEpoch))
+	bt := &blockingTranscriber{entered: make(chan struct{}), release: make(chan struct{})}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		addJob(p, context.Background(), id, bt)
+	}
+
+	returned := make(chan struct{})
+	go func() {
+		p.pollOnce(context.Background())
+		close(returned)
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		<-bt.entered
+	}
+	select {
+	case <-bt.entered:
+		t.Fatalf("more than %d status calls in flight", concurrency)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(bt.release)
+	for i := concurrency; i < jobs; i++ {
+		<-bt.entered
+	}
+	<-returned
+
+	if bt.max != concurrency {
+		t.Errorf("max in-flight status calls = %d, want %d", bt.max, concurrency)
+	}
+	if len(p.jobs) != 0 {
+		t.Errorf("pending jobs = %d, want 0", len(p.jobs))
+	}
+}
+
+type pollerTestKey struct{}
+
+// ctxTranscriber is a fakeTranscriber whose status calls record the context
+// value under pollerTestKey and block until the context ends.
+type ctxTranscriber struct {
+	fakeTranscriber
+	got chan any
+}
+
+func (c *ctxTranscriber) Status(ctx context.Context, transcriptID string) (jobStatus, error) {
+	c.got <- ctx.Value(pollerTestKey{})
+	<-ctx.Done()
+	return jobStatus{}, ctx.Err()
+}
+
+func TestPollJobRunsUnderJobContext(t *testing.T) {
+	p := newPoller(time.Second, 1, newFakeClock(testEpoch))
+	ct := &ctxTranscriber{got: make(chan any, 1)}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), pollerTestKey{}, "job-1"))
+	job := addJob(p, ctx, "t1", ct)
+
+	returned := make(chan struct{})
+	go func() {
+		p.pollOnce(context.Background())
+		close(returned)
+	}()
+
+	if v := <-ct.got; v != "job-1" {
+		t.Errorf("status call context value = %v, want job-1", v)
+	}
+	cancel()
+	<-returned
+
+	if err := <-job.done; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
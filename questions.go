@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// interrogativeWords start a question when they open a sentence.
+var interrogativeWords = map[string]bool{
+	"who": true, "whom": true, "whose": true, "what": true, "when": true, "where": true,
+	"why": true, "how": true, "which": true,
+	"is": true, "are": true, "am": true, "was": true, "were": true, "do": true, "does": true,
+	"did": true, "can": true, "could": true, "will": true, "would": true, "shall": true,
+	"should": true, "may": true, "might": true, "have": true, "has": true,
+	"isn't": true, "aren't": true, "don't": true, "doesn't": true, "didn't": true,
+	"can't": true, "won't": true, "wouldn't": true, "shouldn't": true,
+}
+
+// tagQuestions are the endings that turn a statement into a question, as in "we ship Friday, right".
+var tagQuestions = []string{"right", "correct", "isn't it", "aren't they", "don't you think", "you know what i mean"}
+
+// Reasons reported by detectQuestion.
+const (
+	questionMark          = "question_mark"
+	questionInterrogative = "interrogative"
+	questionTag           = "tag_question"
+)
+
+// detectQuestion reports whether text asks a question, and by which heuristic:
+//
+//   - questionMark: a sentence ends with "?"
+//   - questionTag: the text ends with a tag such as ", right" after a comma
+//   - questionInterrogative: the last sentence has no terminal punctuation and
+//     starts with a question word or an inverted auxiliary, as in "can you share it"
+//
+// Unpunctuated text is common with punctuation turned off, which is why the last
+// heuristic is limited to sentences without a closing "." or "!".
+func detectQuestion(text string) (bool, string) {
+	t := strings.TrimSpace(text)
+	if t == "" {
+		return false, ""
+	}
+	if strings.Contains(t, "?") {
+		return true, questionMark
+	}
+
+	lower := strings.ToLower(strings.TrimRight(t, ".!"))
+	for _, tag := range tagQuestions {
+		if strings.HasSuffix(lower, ", "+tag) {
+			return true, questionTag
+		}
+	}
+
+	if strings.HasSuffix(t, ".") || strings.HasSuffix(t, "!") {
+		return false, ""
+	}
+	last := t
+	if i := strings.LastIndexAny(t, ".!"); i >= 0 {
+		last = t[i+1:]
+	}
+	words := tokenize(last)
+	if len(words) >= 2 && interrogativeWords[words[0]] {
+		return true, questionInterrogative
+	}
+	return false, ""
+}
+
+// Question is an utterance flagged as a question, identified by its index in the transcript.
+type Question struct {
+	Index   int     `json:"index"`
+	Speaker string  `json:"speaker"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+	Reason  string  `json:"reason"`
+}
+
+// findQuestions returns the utterances detected as questions, in transcript order.
+func findQuestions(utterances []CleanUtterance) []Question {
+	out := []Question{}
+	for i, u := range utterances {
+		if ok, reason := detectQuestion(u.Text); ok {
+			out = append(out, Question{Index: i, Speaker: u.Speaker, Start: u.Start, End: u.End, Text: u.Text, Reason: reason})
+		}
+	}
+	return out
+}
+
+// handleGetQuestions returns the questions asked in a transcription.
+// If the transcription is not found, it returns a 404 error.
+func handleGetQuestions(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(findQuestions(data))
+}
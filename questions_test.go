@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestDetectQuestion(t *testing.T) {
+	tests := []struct {
+		text       string
+		want       bool
+		wantReason string
+	}{
+		{"", false, ""},
+		{"Can you share the deck?", true, questionMark},
+		{"We ship Friday, right", true, questionTag},
+		{"That's the plan, correct.", true, questionTag},
+		{"can you share it", true, questionInterrogative},
+		{"OK. what do we do next", true, questionInterrogative},
+		{"what a day.", false, ""},
+		{"we ship on Friday", false, ""},
+		{"why", false, ""},
+	}
+	for _, tt := range tests {
+		got, reason := detectQuestion(tt.text)
+		if got != tt.want || reason != tt.wantReason {
+			t.Errorf("detectQuestion(%q) = %v, %q, want %v, %q", tt.text, got, reason, tt.want, tt.wantReason)
+		}
+	}
+}
+
+func TestFindQuestions(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "Welcome everyone."},
+		{Speaker: "B", Text: "Is this recorded?", Start: 3, End: 4},
+	}
+	got := findQuestions(utterances)
+	if len(got) != 1 || got[0].Index != 1 || got[0].Speaker != "B" || got[0].Reason != questionMark {
+		t.Errorf("findQuestions = %+v, want the second utterance", got)
+	}
+}
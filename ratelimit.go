@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrRateLimited is returned when the provider still answers 429 Too Many Requests
+// after all retries. Callers can tell it apart from outages and other failures.
+var ErrRateLimited = errors.New("rate limited by transcription provider")
+
+// maxRetryAfter caps how long a single Retry-After header can make a request wait.
+const maxRetryAfter = time.Minute
+
+// rateLimitTransport retries requests the provider rejects with 429,
+// waiting for the Retry-After header if present and backing off exponentially otherwise.
+// Other statuses, including 5xx, are left to retryTransport.
+// Requests whose body cannot be replayed are not retried; uploaded audio can be, see replayableBody.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	clock Clock
+}
+
+// RoundTrip sends the request, retrying up to RATE_LIMIT_RETRIES times on 429.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = withReplayableBody(req)
+	backoff := cfg.RateLimitBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		if attempt >= cfg.RateLimitRetries {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w after %d retries", ErrRateLimited, attempt)
+		}
+
+		if err := takeRetry(req.Context(), resp.Status); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		wait := retryAfter(resp.Header.Get("Retry-After"), t.clock.Now())
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		resp.Body.Close()
+		loggerFrom(req.Context()).Warn("Provider rate limited, retrying", "path", req.URL.Path, "wait", wait.String())
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-t.clock.After(wait):
+		}
+
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+// It returns zero when the header is missing or invalid, and never more than maxRetryAfter.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(header); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		d = at.Sub(now)
+	}
+	if d < 0 {
+		return 0
+	}
+	return min(d, maxRetryAfter)
+}
+
+// providerHTTPClient is the HTTP client used for all calls to the provider.
+// Transient failures are retried around the rate limit handling, so a 429
+// that outlasts its own retries is not retried again. Non-JSON responses are
+// caught below both, so they are retried like network errors.
+var providerHTTPClient = &http.Client{
+	Transport: &retryTransport{
+		base:  &rateLimitTransport{base: &jsonTransport{base: http.DefaultTransport}, clock: realClock{}},
+		clock: realClock{},
+	},
+}
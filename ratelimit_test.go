@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers requests with the given statuses in order, recording the bodies it saw.
+// A status of 0 answers with a network error.
+type scriptedTransport struct {
+	statuses []int
+	header   http.Header
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bodies = append(s.bodies, body)
+	status := s.statuses[min(len(s.bodies), len(s.statuses))-1]
+	if status == 0 {
+		return nil, errors.New("connection reset")
+	}
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: s.header.Clone(), Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+func (s *scriptedTransport) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-5":                            0,
+		"soon":                          0,
+		"3600":                          maxRetryAfter,
+		"Fri, 01 Mar 2024 12:00:10 GMT": 10 * time.Second,
+		"Fri, 01 Mar 2024 11:00:00 GMT": 0,
+	}
+	for header, want := range tests {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestRateLimitTransportRetriesThenSucceeds(t *testing.T) {
+	useConfig(t, func(c *config) { c.RateLimitRetries, c.RateLimitBackoff = 3, time.Second })
+	clock := newFakeClock(testEpoch)
+	base := &scriptedTransport{statuses: []int{429, 429, 200}, header: http.Header{"Retry-After": {"2"}}}
+	transport := &rateLimitTransport{base: base, clock: clock}
+
+	req, _ := http.NewRequest("POST", "https://provider.test/v2/upload", bytes.NewReader([]byte("audio")))
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := transport.RoundTrip(req)
+		done <- result{resp, err}
+	}()
+
+	for i := 0; i < 2; i++ {
+		clock.blockUntilWaiters(t, 1)
+		clock.Advance(2 * time.Second)
+	}
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if res.resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", res.resp.StatusCode)
+	}
+	if sent := base.sent(); len(sent) != 3 || sent[2] != "audio" {
+		t.Errorf("sent %q, want the body three times", sent)
+	}
+}
+
+func TestRateLimitTransportGivesUp(t *testing.T) {
+	useConfig(t, func(c *config) { c.RateLimitRetries, c.RateLimitBackoff = 1, time.Second })
+	clock := newFakeClock(testEpoch)
+	base := &scriptedTransport{statuses: []int{429}}
+	transport := &rateLimitTransport{base: base, clock: clock}
+
+	req, _ := http.NewRequest("GET", "https://provider.test/v2/transcript/1", nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req)
+		done <- err
+	}()
+	clock.blockUntilWaiters(t, 1)
+	clock.Advance(cfg.RateLimitBackoff)
+
+	if err := <-done; !errors.Is(err, ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+	if n := len(base.sent()); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+}
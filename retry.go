@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is returned when a provider call should be retried
+// but the request it is made for has used up its retry budget.
+var ErrRetryBudgetExhausted = errors.New("retry budget of the request exhausted")
+
+// retryBudget is the number of retries left to all provider calls made for one
+// request, such as its submit, polls, and fetches. It is shared by retryTransport
+// and rateLimitTransport, so one flaky call cannot use every retry of the request.
+type retryBudget struct {
+	mu        sync.Mutex
+	size      int
+	remaining int
+}
+
+// take uses up one retry, reporting false when none are left.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining == 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// retryBudgetKey is the context key of the request's retryBudget.
+type retryBudgetKey struct{}
+
+// withRetryBudget returns ctx carrying a new budget of size retries.
+// A size of zero or less leaves the retries of ctx unlimited.
+func withRetryBudget(ctx context.Context, size int) context.Context {
+	if size <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, retryBudgetKey{}, &retryBudget{size: size, remaining: size})
+}
+
+// retryBudgetFrom returns the budget carried by ctx, or nil if retries are unlimited.
+func retryBudgetFrom(ctx context.Context) *retryBudget {
+	b, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return b
+}
+
+// takeRetry uses up one retry of the budget of ctx. When the budget is
+// exhausted, it returns an ErrRetryBudgetExhausted error naming cause,
+// the failure that would have been retried.
+func takeRetry(ctx context.Context, cause string) error {
+	b := retryBudgetFrom(ctx)
+	if b == nil || b.take() {
+		return nil
+	}
+	return fmt.Errorf("%w after %d retries, last failure: %s", ErrRetryBudgetExhausted, b.size, cause)
+}
+
+// retryableStatuses are the provider statuses worth retrying: transient server
+// failures. 429 is handled by rateLimitTransport, and other 4xx such as auth
+// failures will not succeed on a retry.
+var retryableStatuses = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retryTransport retries requests that fail with a network error or a
+// retryableStatuses response, up to PROVIDER_RETRY_ATTEMPTS attempts in total.
+// The waits grow exponentially from PROVIDER_RETRY_BASE_DELAY with random jitter,
+// and a retry is skipped when it would wait past the request's deadline.
+// Every retry draws on the request's retryBudget, if it has one.
+// Requests whose body cannot be replayed are not retried; uploaded audio can be, see replayableBody.
+type retryTransport struct {
+	base  http.RoundTripper
+	clock Clock
+}
+
+// RoundTrip sends the request, retrying transient failures.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = withReplayableBody(req)
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if !shouldRetry(req, resp, err) || attempt >= cfg.ProviderRetryAttempts {
+			return resp, err
+		}
+
+		wait := backoffDelay(cfg.ProviderRetryBase, attempt)
+		if deadline, ok := req.Context().Deadline(); ok && t.clock.Now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		if err != nil {
+			if budgetErr := takeRetry(req.Context(), err.Error()); budgetErr != nil {
+				return nil, budgetErr
+			}
+			loggerFrom(req.Context()).Warn("Provider request failed, retrying", "path", req.URL.Path, "error", err, "wait", wait.String())
+		} else {
+			resp.Body.Close()
+			if budgetErr := takeRetry(req.Context(), resp.Status); budgetErr != nil {
+				return nil, budgetErr
+			}
+			loggerFrom(req.Context()).Warn("Provider request failed, retrying", "path", req.URL.Path, "http_status", resp.StatusCode, "wait", wait.String())
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-t.clock.After(wait):
+		}
+
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// shouldRetry reports whether a request ending with resp or err may be sent again.
+// Cancellations, deadlines, exhausted rate limits, and exhausted retry budgets are final.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrRateLimited) && !errors.Is(err, ErrRetryBudgetExhausted)
+	}
+	return retryableStatuses[resp.StatusCode]
+}
+
+// backoffDelay returns the wait before the retry following attempt:
+// base doubled for every earlier attempt, of which the upper half is random jitter
+// so clients failing together don't retry in lockstep.
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << (attempt - 1)
+	if d <= 0 {
+		return base
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// replayableBody is a request body that can produce a fresh copy of itself, like audioBody.
+// The provider SDK builds its requests from a plain io.Reader, so net/http only
+// sets GetBody for in-memory readers; this lets other bodies be retried too.
+type replayableBody interface {
+	reopen() (io.ReadCloser, error)
+	length() int64
+}
+
+// withReplayableBody returns req with GetBody and ContentLength set from its body,
+// if the body is a replayableBody and GetBody is not set yet.
+func withReplayableBody(req *http.Request) *http.Request {
+	body, ok := req.Body.(replayableBody)
+	if !ok || req.GetBody != nil {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.GetBody = body.reopen
+	if req.ContentLength == 0 {
+		req.ContentLength = body.length()
+	}
+	return req
+}
+
+// rewindRequest returns req ready to be sent again, with a fresh copy of its body.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = body
+	return req, nil
+}
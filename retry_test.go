@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt := 1; attempt <= 4; attempt++ {
+		full := base << (attempt - 1)
+		for i := 0; i < 20; i++ {
+			if d := backoffDelay(base, attempt); d < full/2 || d > full {
+				t.Fatalf("backoffDelay(attempt %d) = %v, want within [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	get, _ := http.NewRequest("GET", "https://provider.test", nil)
+	stream, _ := http.NewRequest("POST", "https://provider.test", strings.NewReader("x"))
+	stream.GetBody = nil
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"network error", get, nil, errors.New("reset"), true},
+		{"503", get, &http.Response{StatusCode: 503}, nil, true},
+		{"404", get, &http.Response{StatusCode: 404}, nil, false},
+		{"cancelled", get, nil, context.Canceled, false},
+		{"deadline", get, nil, context.DeadlineExceeded, false},
+		{"exhausted rate limit", get, nil, ErrRateLimited, false},
+		{"exhausted budget", get, nil, ErrRetryBudgetExhausted, false},
+		{"body not replayable", stream, &http.Response{StatusCode: 503}, nil, false},
+	}
+	for _, tt := range tests {
+		if got := shouldRetry(tt.req, tt.resp, tt.err); got != tt.want {
+			t.Errorf("%s: shouldRetry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryBudgetExhaustion(t *testing.T) {
+	useConfig(t, func(c *config) { c.ProviderRetryAttempts, c.ProviderRetryBase = 5, 10*time.Millisecond })
+	clock := newFakeClock(testEpoch)
+	base := &scriptedTransport{statuses: []int{503}}
+	transport := &retryTransport{base: base, clock: clock}
+
+	ctx := withRetryBudget(context.Background(), 1)
+	req, _ := http.NewRequestWithContext(ctx, "GET", "https://provider.test/v2/transcript/1", nil)
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req)
+		done <- err
+	}()
+	clock.blockUntilWaiters(t, 1)
+	clock.Advance(time.Second)
+
+	err := <-done
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrRetryBudgetExhausted", err)
+	}
+	if !strings.Contains(err.Error(), "Service Unavailable") {
+		t.Errorf("err = %v, want the last failure named", err)
+	}
+	if n := len(base.sent()); n != 2 {
+		t.Errorf("attempts = %d, want 2: the first and the one retry of the budget", n)
+	}
+
+	// The budget is shared: a later call of the same request gets no retry at all.
+	base2 := &scriptedTransport{statuses: []int{503}}
+	req2, _ := http.NewRequestWithContext(ctx, "GET", "https://provider.test/v2/transcript/1", nil)
+	if _, err := (&retryTransport{base: base2, clock: clock}).RoundTrip(req2); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("second call err = %v, want ErrRetryBudgetExhausted", err)
+	}
+	if n := len(base2.sent()); n != 1 {
+		t.Errorf("second call attempts = %d, want 1", n)
+	}
+}
+
+func TestRetryTransportWithoutBudget(t *testing.T) {
+	useConfig(t, func(c *config) { c.ProviderRetryAttempts, c.ProviderRetryBase = 3, 10*time.Millisecond })
+	clock := newFakeClock(testEpoch)
+	base := &scriptedTransport{statuses: []int{0, 502, 200}}
+	transport := &retryTransport{base: base, clock: clock}
+
+	req, _ := http.NewRequest("GET", "https://provider.test/v2/transcript/1", nil)
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, _ := transport.RoundTrip(req)
+		done <- resp
+	}()
+	for i := 0; i < 2; i++ {
+		clock.blockUntilWaiters(t, 1)
+		clock.Advance(time.Second)
+	}
+	if resp := <-done; resp == nil || resp.StatusCode != 200 {
+		t.Errorf("resp = %+v, want the 200 after two retries", resp)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Heuristics for ranking speakers when assigning roles with ?roles=.
+const (
+	// roleByTalkTime ranks speakers by total talk time, most talkative first.
+	roleByTalkTime = "talk_time"
+	// roleByFirstSpeaker ranks speakers by when they first spoke.
+	roleByFirstSpeaker = "first_speaker"
+)
+
+// rankSpeakers orders the speaker labels of utterances by heuristic.
+// Utterances without a speaker label are left out. Ties in talk time
+// keep the order of first appearance.
+func rankSpeakers(utterances []CleanUtterance, heuristic string) []string {
+	var labels []string
+	talk := make(map[string]float64)
+	for _, u := range utterances {
+		if u.Speaker == "" {
+			continue
+		}
+		if _, ok := talk[u.Speaker]; !ok {
+			labels = append(labels, u.Speaker)
+		}
+		talk[u.Speaker] += u.End - u.Start
+	}
+	if heuristic == roleByTalkTime {
+		sort.SliceStable(labels, func(i, j int) bool { return talk[labels[i]] > talk[labels[j]] })
+	}
+	return labels
+}
+
+// assignRoles maps the speaker labels of utterances to roles, ranked by heuristic.
+// The top-ranked speaker gets the first role, the next one the second, and so on;
+// every speaker past the end of roles shares the last role, numbered from 1 so that
+// the names stay distinct, as in "Guest 1" and "Guest 2". A last role given to a single
+// speaker is not numbered. With no roles it returns an empty map.
+func assignRoles(utterances []CleanUtterance, heuristic string, roles []string) map[string]string {
+	names := make(map[string]string)
+	if len(roles) == 0 {
+		return names
+	}
+
+	ranked := rankSpeakers(utterances, heuristic)
+	last := len(roles) - 1
+	shared := len(ranked) - last
+	for i, label := range ranked {
+		switch {
+		case i < last:
+			names[label] = roles[i]
+		case shared == 1:
+			names[label] = roles[last]
+		default:
+			names[label] = fmt.Sprintf("%s %d", roles[last], i-last+1)
+		}
+	}
+	return names
+}
+
+// parseRoleHeuristic validates the roles query option; empty means no roles are assigned.
+func parseRoleHeuristic(v string) (string, error) {
+	switch v {
+	case "", roleByTalkTime, roleByFirstSpeaker:
+		return v, nil
+	}
+	return "", fmt.Errorf("roles must be %q or %q", roleByTalkTime, roleByFirstSpeaker)
+}
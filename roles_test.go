@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// roleMeeting has A speaking first, B talking longest, and C least.
+var roleMeeting = []CleanUtterance{
+	{Speaker: "A", Start: 0, End: 2},
+	{Speaker: "B", Start: 2, End: 10},
+	{Text: "unlabeled", Start: 10, End: 30},
+	{Speaker: "C", Start: 30, End: 31},
+	{Speaker: "A", Start: 31, End: 33},
+}
+
+func TestAssignRoles(t *testing.T) {
+	tests := []struct {
+		name      string
+		heuristic string
+		roles     []string
+		want      map[string]string
+	}{
+		{"talk time", roleByTalkTime, []string{"Host", "Guest"}, map[string]string{"B": "Host", "A": "Guest 1", "C": "Guest 2"}},
+		{"first speaker", roleByFirstSpeaker, []string{"Host", "Guest"}, map[string]string{"A": "Host", "B": "Guest 1", "C": "Guest 2"}},
+		{"single shared role", roleByTalkTime, []string{"Host", "Cohost", "Guest"}, map[string]string{"B": "Host", "A": "Cohost", "C": "Guest"}},
+		{"more roles than speakers", roleByFirstSpeaker, []string{"Host", "Cohost", "Guest", "Extra"}, map[string]string{"A": "Host", "B": "Cohost", "C": "Guest"}},
+		{"one role", roleByTalkTime, []string{"Participant"}, map[string]string{"B": "Participant 1", "A": "Participant 2", "C": "Participant 3"}},
+		{"no roles", roleByTalkTime, nil, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignRoles(roleMeeting, tt.heuristic, tt.roles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankSpeakersTalkTimeTies(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "B", Start: 0, End: 1},
+		{Speaker: "A", Start: 1, End: 2},
+		{Speaker: "C", Start: 2, End: 4},
+	}
+	if got, want := rankSpeakers(utterances, roleByTalkTime), []string{"C", "B", "A"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestRenameSpeakerAttributesFollowsRoles(t *testing.T) {
+	attrs := map[string]SpeakerDemographics{"A": {Gender: "female"}, "B": {Age: "40-50"}, "Z": {Gender: "male"}}
+	roles := assignRoles(roleMeeting, roleByTalkTime, []string{"Host", "Guest"})
+
+	renamed, err := renameSpeakers(roleMeeting, roles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := speakerProfiles(renamed, renameSpeakerAttributes(attrs, roles))
+	want := []SpeakerProfile{
+		{Speaker: "Guest 1", Gender: "female", Age: attributeUnavailable},
+		{Speaker: "Host", Gender: attributeUnavailable, Age: "40-50"},
+		{Speaker: "Guest 2", Gender: attributeUnavailable, Age: attributeUnavailable},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("profiles = %+v, want %+v", got, want)
+	}
+	if _, ok := renameSpeakerAttributes(attrs, roles)["Z"]; !ok {
+		t.Error("attributes of a label without a role were dropped")
+	}
+	if renameSpeakerAttributes(nil, roles) != nil {
+		t.Error("renameSpeakerAttributes(nil) is not nil")
+	}
+}
+
+func TestCompleteTranscriptionRenamesAttributesWithRoles(t *testing.T) {
+	useTestPoller(t)
+	useConfig(t, func(c *config) {
+		c.SpeakerAttributes = true
+		c.SpeakerRoles = []string{"Host", "Guest"}
+	})
+	const id = "roles-attrs"
+	t.Cleanup(func() { deleteTranscription(id) })
+	at := &attrTranscriber{
+		fakeTranscriber: fakeTranscriber{utterances: [][]Utterance{{
+			{Speaker: "A", Text: "hi", Start: 0, End: 1000},
+			{Speaker: "B", Text: "hello there everyone", Start: 1000, End: 5000},
+		}}},
+		attrs: map[string]SpeakerDemographics{"A": {Gender: "female", Age: "30-40"}, "B": {Gender: "male"}},
+	}
+
+	opts := ingestOptions{RoleHeuristic: roleByTalkTime}
+	if _, _, err := completeTranscription(context.Background(), at, "transcript-1", id, &assemblyai.TranscriptOptionalParams{}, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []SpeakerProfile
+	if err := json.NewDecoder(serveTranscription(handleGetSpeakers, id, "").Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []SpeakerProfile{
+		{Speaker: "Guest", Gender: "female", Age: "30-40"},
+		{Speaker: "Host", Gender: "male", Age: attributeUnavailable},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("speakers = %+v, want %+v", got, want)
+	}
+}
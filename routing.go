@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// wavDuration estimates the duration in seconds of a WAV file from its header.
+// It reads the byte rate of the fmt chunk and the size of the data chunk,
+// falling back to the bytes present when the data size is unset, as written by
+// some streaming recorders. data holds the first bytes of a file of total bytes.
+// It reports false when data is not a readable WAV header.
+func wavDuration(data []byte, total int) (float64, bool) {
+	if len(data) < 12 || !bytes.Equal(data[:4], []byte("RIFF")) || !bytes.Equal(data[8:12], []byte("WAVE")) {
+		return 0, false
+	}
+
+	var byteRate uint32
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		switch id {
+		case "fmt ":
+			if body+12 > len(data) {
+				return 0, false
+			}
+			byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+		case "data":
+			if byteRate == 0 {
+				return 0, false
+			}
+			if size == 0 || body+size > total {
+				size = total - body
+			}
+			return float64(size) / float64(byteRate), true
+		}
+		pos = body + size + size%2
+	}
+	return 0, false
+}
+
+// routeTranscriber picks the transcriber for audio of the given duration in seconds.
+// Audio shorter than threshold seconds goes to short and everything else to long,
+// including audio of unknown duration, which favours accuracy.
+func routeTranscriber(seconds float64, known bool, threshold float64, short, long Transcriber) Transcriber {
+	if known && seconds < threshold {
+		return short
+	}
+	return long
+}
+
+// newUploadTranscriber returns the transcriber for an uploaded audio file.
+// With ROUTE_SHORT_SECONDS set, clips shorter than it use SHORT_SPEECH_MODEL
+// and longer ones LONG_SPEECH_MODEL; otherwise the provider default is used.
+// It is a variable so uploads can run against a fake provider.
+var newUploadTranscriber = func(apiKey string, audio *uploadedAudio) Transcriber {
+	if cfg.RouteShortSeconds <= 0 {
+		return newAssemblyAITranscriber(apiKey, "")
+	}
+	seconds, known := wavDuration(audio.header(), audio.Len())
+	return routeTranscriber(seconds, known, cfg.RouteShortSeconds,
+		newAssemblyAITranscriber(apiKey, cfg.ShortSpeechModel),
+		newAssemblyAITranscriber(apiKey, cfg.LongSpeechModel))
+}
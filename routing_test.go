@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testWAV returns a WAV header with the given byte rate and data size, followed by n bytes of audio.
+func testWAV(byteRate, dataSize uint32, n int) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WAVE")
+	fmtChunk := make([]byte, 8+16)
+	copy(fmtChunk, "fmt ")
+	binary.LittleEndian.PutUint32(fmtChunk[4:], 16)
+	binary.LittleEndian.PutUint32(fmtChunk[8+8:], byteRate)
+	b = append(b, fmtChunk...)
+	data := make([]byte, 8)
+	copy(data, "data")
+	binary.LittleEndian.PutUint32(data[4:], dataSize)
+	b = append(b, data...)
+	return append(b, make([]byte, n)...)
+}
+
+func TestWavDuration(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		total     int
+		want      float64
+		wantKnown bool
+	}{
+		{"from data size", testWAV(32000, 320000, 10), 44 + 320000, 10, true},
+		{"header only, size from the header", testWAV(32000, 64000, 0), 44 + 64000, 2, true},
+		{"unset size uses the total", testWAV(32000, 0, 0), 44 + 96000, 3, true},
+		{"not a WAV", []byte("ID3\x04 some mp3"), 100, 0, false},
+		{"truncated", []byte("RIFF\x00\x00"), 6, 0, false},
+		{"no byte rate", testWAV(0, 100, 0), 144, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, known := wavDuration(tt.data, tt.total)
+			if got != tt.want || known != tt.wantKnown {
+				t.Errorf("wavDuration = %v, %v, want %v, %v", got, known, tt.want, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestRouteTranscriber(t *testing.T) {
+	short, long := &fakeTranscriber{name: "short"}, &fakeTranscriber{name: "long"}
+	tests := []struct {
+		name    string
+		seconds float64
+		known   bool
+		want    string
+	}{
+		{"short clip", 30, true, "short"},
+		{"at the threshold", 60, true, "long"},
+		{"long clip", 600, true, "long"},
+		{"unknown duration", 0, false, "long"},
+	}
+	for _, tt := range tests {
+		if got := routeTranscriber(tt.seconds, tt.known, 60, short, long).Name(); got != tt.want {
+			t.Errorf("%s: routed to %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewUploadTranscriberRoutesByDuration(t *testing.T) {
+	useConfig(t, func(c *config) {
+		c.RouteShortSeconds = 60
+		c.ShortSpeechModel, c.LongSpeechModel = "nano", "best"
+	})
+
+	tests := []struct {
+		name    string
+		seconds uint32
+		want    string
+	}{
+		{"short", 10, "assemblyai/nano"},
+		{"long", 120, "assemblyai/best"},
+	}
+	for _, tt := range tests {
+		a := &uploadedAudio{threshold: 1 << 30}
+		a.write(testWAV(1000, tt.seconds*1000, int(tt.seconds*1000)))
+		if got := newUploadTranscriber("key", a).Name(); got != tt.want {
+			t.Errorf("%s: %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	cfg.RouteShortSeconds = 0
+	if got := newUploadTranscriber("key", &uploadedAudio{}).Name(); got != "assemblyai" {
+		t.Errorf("routing off: %s, want the provider default", got)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SCC caption limits: rows are 32 columns wide and each pop-on caption uses up to two rows.
+const (
+	sccRowWidth       = 32
+	sccRowsPerCaption = 2
+)
+
+// SCC control codes, with parity, as used for pop-on captions.
+const (
+	sccResumeLoading   = "9420" // RCL
+	sccEraseNonDisplay = "94ae" // ENM
+	sccEraseDisplayed  = "942c" // EDM
+	sccEndOfCaption    = "942f" // EOC
+	sccRow14           = "94d0" // PAC, row 14 column 0
+	sccRow15           = "9470" // PAC, row 15 column 0
+)
+
+// sccTwice repeats a control code, since decoders may drop a single one.
+func sccTwice(code string) string {
+	return code + " " + code
+}
+
+// sccParity sets the high bit of a 7-bit character so the byte has odd parity, as CEA-608 requires.
+func sccParity(b byte) byte {
+	if bits.OnesCount8(b)%2 == 0 {
+		return b | 0x80
+	}
+	return b
+}
+
+// sccEncodeText encodes text as CEA-608 character bytes in hex pairs.
+// Characters outside printable ASCII are dropped, and an odd count is padded with a null.
+func sccEncodeText(text string) string {
+	var raw []byte
+	for _, r := range text {
+		if r >= 0x20 && r < 0x7f {
+			raw = append(raw, sccParity(byte(r)))
+		}
+	}
+	if len(raw)%2 == 1 {
+		raw = append(raw, 0x80)
+	}
+
+	pairs := make([]string, 0, len(raw)/2)
+	for i := 0; i < len(raw); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%02x%02x", raw[i], raw[i+1]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// formatSCCTimecode formats a time in seconds as a non-drop-frame SCC timecode HH:MM:SS:FF.
+// Frames are counted at fps and the timecode advances at the nearest whole frame rate.
+func formatSCCTimecode(seconds, fps float64) string {
+	nominal := int64(math.Round(fps))
+	frames := int64(math.Round(seconds * fps))
+	ff := frames % nominal
+	total := frames / nominal
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", total/3600, (total/60)%60, total%60, ff)
+}
+
+// wrapCaptionRows word-wraps text into rows of at most width characters.
+// Words longer than a row are split.
+func wrapCaptionRows(text string, width int) []string {
+	var rows []string
+	row := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > width {
+			if row != "" {
+				rows = append(rows, row)
+				row = ""
+			}
+			rows = append(rows, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case row == "":
+			row = word
+		case len(row)+1+len(word) <= width:
+			row += " " + word
+		default:
+			rows = append(rows, row)
+			row = word
+		}
+	}
+	if row != "" {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// sccCaption is one pop-on caption with its display time in seconds.
+type sccCaption struct {
+	Start, End float64
+	Rows       []string
+}
+
+// sccCaptions splits utterances into pop-on captions of at most two rows.
+// A long utterance becomes several captions, sharing its duration by length.
+func sccCaptions(utterances []CleanUtterance) []sccCaption {
+	var out []sccCaption
+	for _, u := range utterances {
+		rows := wrapCaptionRows(u.Text, sccRowWidth)
+		total := 0
+		for _, r := range rows {
+			total += len(r)
+		}
+
+		start := u.Start
+		for i := 0; i < len(rows); i += sccRowsPerCaption {
+			group := rows[i:min(i+sccRowsPerCaption, len(rows))]
+			n := 0
+			for _, r := range group {
+				n += len(r)
+			}
+			end := start + (u.End-u.Start)*float64(n)/float64(total)
+			out = append(out, sccCaption{Start: start, End: end, Rows: group})
+			start = end
+		}
+	}
+	return out
+}
+
+// renderSCC renders utterances as a Scenarist SCC document of pop-on captions at fps.
+// Each caption is loaded into the off-screen buffer and displayed at its start,
+// then erased at its end unless the next caption replaces it right away.
+func renderSCC(utterances []CleanUtterance, fps float64) string {
+	var b strings.Builder
+	b.WriteString("Scenarist_SCC V1.0\n")
+
+	captions := sccCaptions(utterances)
+	for i, c := range captions {
+		codes := []string{sccTwice(sccResumeLoading), sccTwice(sccEraseNonDisplay)}
+		rowCodes := []string{sccRow15}
+		if len(c.Rows) == 2 {
+			rowCodes = []string{sccRow14, sccRow15}
+		}
+		for j, row := range c.Rows {
+			codes = append(codes, sccTwice(rowCodes[j]), sccEncodeText(row))
+		}
+		codes = append(codes, sccTwice(sccEndOfCaption))
+		fmt.Fprintf(&b, "\n%s\t%s\n", formatSCCTimecode(c.Start, fps), strings.Join(codes, " "))
+
+		if i+1 == len(captions) || captions[i+1].Start > c.End {
+			fmt.Fprintf(&b, "\n%s\t%s\n", formatSCCTimecode(c.End, fps), sccTwice(sccEraseDisplayed))
+		}
+	}
+	return b.String()
+}
+
+// handleGetSCC retrieves the transcription as SCC closed captions at SCC_FRAME_RATE.
+// If the transcription is not found, it returns a 404 error.
+func handleGetSCC(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	gen := exports.generation(id)
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	out := exports.get(id, "scc", gen, func() string { return renderSCC(data, cfg.SCCFrameRate) })
+	writeAttachment(w, r, id, "scc", "text/plain; charset=utf-8", out)
+}
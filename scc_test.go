@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestRenderSCC(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "Hi", Start: 1, End: 2},
+		{Text: "Ok.", Start: 2, End: 3.5},
+		{Text: "Hi", Start: 4, End: 5},
+	}
+
+	// The second caption replaces the first right away, so only the
+	// gap before the third and the end of the last erase the display.
+	got := renderSCC(utterances, 30)
+	want := "Scenarist_SCC V1.0\n" +
+		"\n00:00:01:00\t9420 9420 94ae 94ae 9470 9470 c8e9 942f 942f\n" +
+		"\n00:00:02:00\t9420 9420 94ae 94ae 9470 9470 4f6b ae80 942f 942f\n" +
+		"\n00:00:03:15\t942c 942c\n" +
+		"\n00:00:04:00\t9420 9420 94ae 94ae 9470 9470 c8e9 942f 942f\n" +
+		"\n00:00:05:00\t942c 942c\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderSCCTwoRows(t *testing.T) {
+	got := renderSCC([]CleanUtterance{{Text: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa b", Start: 0, End: 1}}, 25)
+	a := "6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161 6161"
+	want := "Scenarist_SCC V1.0\n" +
+		"\n00:00:00:00\t9420 9420 94ae 94ae 94d0 94d0 " + a + " 9470 9470 6280 942f 942f\n" +
+		"\n00:00:01:00\t942c 942c\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Defaults and bounds of the ?limit parameter of handleSearch.
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 1000
+)
+
+// SearchHit is an utterance matching a search query.
+type SearchHit struct {
+	Speaker string  `json:"speaker"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Text    string  `json:"text"`
+}
+
+// SearchResult groups the hits of one transcription.
+type SearchResult struct {
+	ConnectionID string      `json:"connection_id"`
+	Hits         []SearchHit `json:"hits"`
+}
+
+// SearchResponse is the result of a search. Truncated reports whether
+// more utterances matched than the limit allowed.
+type SearchResponse struct {
+	Query     string         `json:"query"`
+	Total     int            `json:"total"`
+	Truncated bool           `json:"truncated"`
+	Results   []SearchResult `json:"results"`
+}
+
+// searchUtterances returns the utterances containing query, case-insensitively,
+// up to limit hits. query must already be lowercased.
+func searchUtterances(utterances []CleanUtterance, query string, limit int) []SearchHit {
+	hits := []SearchHit{}
+	for _, u := range utterances {
+		if len(hits) == limit {
+			break
+		}
+		if strings.Contains(strings.ToLower(u.Text), query) {
+			hits = append(hits, SearchHit{Speaker: u.Speaker, Start: u.Start, End: u.End, Text: u.Text})
+		}
+	}
+	return hits
+}
+
+// handleSearch finds the utterances containing ?q across all completed transcriptions,
+// grouped by transcription, oldest first. At most ?limit hits are returned in total (default 50).
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if query == "" {
+		http.Error(w, "q must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	resp := SearchResponse{Query: r.URL.Query().Get("q"), Results: []SearchResult{}}
+	for _, stored := range listTranscriptions(0, math.MaxInt) {
+		entry, ok, err := getTranscription(stored.ID)
+		if err != nil {
+			loggerFrom(r.Context()).Error("Failed to read stored transcription", "conn_id", stored.ID, "error", err)
+			continue
+		}
+		if !ok || entry.Status == statusFailed {
+			continue
+		}
+
+		// One hit past the limit tells whether the results were cut off.
+		hits := searchUtterances(entry.Utterances, query, limit-resp.Total+1)
+		if resp.Total+len(hits) > limit {
+			hits = hits[:limit-resp.Total]
+			resp.Truncated = true
+		}
+		if len(hits) > 0 {
+			resp.Results = append(resp.Results, SearchResult{ConnectionID: stored.ID, Hits: hits})
+			resp.Total += len(hits)
+		}
+		if resp.Truncated {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HighlightedWord is a word of the transcript, flagged when it is one of the highlighted keywords.
+type HighlightedWord struct {
+	CleanWord
+	Speaker string `json:"speaker"`
+	Matched bool   `json:"matched"`
+}
+
+// highlightWords flattens the words of all utterances, in order, and flags those
+// equal to a keyword, case-insensitively and ignoring surrounding punctuation,
+// so "Budget," matches "budget". Keywords must be lowercase.
+func highlightWords(utterances []CleanUtterance, kws []string) []HighlightedWord {
+	keywords := make(map[string]bool, len(kws))
+	for _, k := range kws {
+		keywords[k] = true
+	}
+
+	out := []HighlightedWord{}
+	for _, u := range utterances {
+		for _, w := range u.Words {
+			bare := strings.ToLower(strings.TrimFunc(w.Text, unicode.IsPunct))
+			out = append(out, HighlightedWord{CleanWord: w, Speaker: u.Speaker, Matched: keywords[bare]})
+		}
+	}
+	return out
+}
+
+// handleGetWords returns every word of a transcription with its timing, flagging
+// the words listed in the comma-separated ?highlight keywords.
+// It returns 422 when the transcription has no word timestamps, and a 404 error
+// if the transcription is not found.
+func handleGetWords(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+	if !hasWordTimestamps(data) {
+		http.Error(w, "Word timestamps not available", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var kws []string
+	if h := r.URL.Query().Get("highlight"); r.URL.Query().Has("highlight") {
+		var err error
+		if kws, err = parseKeywords(h); err != nil {
+			http.Error(w, "highlight must list at least one keyword", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlightWords(data, kws))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHighlightWords(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Words: []CleanWord{{Text: "The", Start: 0, End: 0.2}, {Text: "Budget,", Start: 0.2, End: 0.6}, {Text: "budgets", Start: 0.6, End: 1}}},
+		{Text: "no word timings"},
+		{Speaker: "B", Words: []CleanWord{{Text: "DEADLINE!", Start: 2, End: 2.5}, {Text: "dead", Start: 2.5, End: 2.7}}},
+	}
+	kws, err := parseKeywords("budget, Deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := highlightWords(utterances, kws)
+	want := []HighlightedWord{
+		{CleanWord: CleanWord{Text: "The", Start: 0, End: 0.2}, Speaker: "A"},
+		{CleanWord: CleanWord{Text: "Budget,", Start: 0.2, End: 0.6}, Speaker: "A", Matched: true},
+		{CleanWord: CleanWord{Text: "budgets", Start: 0.6, End: 1}, Speaker: "A"},
+		{CleanWord: CleanWord{Text: "DEADLINE!", Start: 2, End: 2.5}, Speaker: "B", Matched: true},
+		{CleanWord: CleanWord{Text: "dead", Start: 2.5, End: 2.7}, Speaker: "B"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("highlightWords =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, w := range highlightWords(utterances, nil) {
+		if w.Matched {
+			t.Errorf("%q matched without keywords", w.Text)
+		}
+	}
+}
+
+func TestHandleGetWords(t *testing.T) {
+	storeTestTranscription(t, "words-1", []CleanUtterance{{Text: "hi", Words: []CleanWord{{Text: "hi", End: 1}}}})
+	storeTestTranscription(t, "words-none", []CleanUtterance{{Text: "hi"}})
+
+	tests := []struct {
+		id, query string
+		want      int
+	}{
+		{"words-1", "", http.StatusOK},
+		{"words-1", "highlight=hi", http.StatusOK},
+		{"words-1", "highlight=,", http.StatusBadRequest},
+		{"words-none", "highlight=hi", http.StatusUnprocessableEntity},
+		{"words-missing", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serveTranscription(handleGetWords, tt.id, tt.query); w.Code != tt.want {
+			t.Errorf("%s?%s: status = %d, want %d", tt.id, tt.query, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// sentencePauseSeconds is the silence after a sentence at which a pause alone
+// counts as full evidence of a boundary.
+const sentencePauseSeconds = 0.5
+
+// Sentence is one sentence of an utterance.
+// BoundaryConfidence, between 0 and 1, is how reliable the split after the sentence is.
+type Sentence struct {
+	Text               string  `json:"text"`
+	Speaker            string  `json:"speaker"`
+	Start              float64 `json:"start"`
+	End                float64 `json:"end"`
+	BoundaryConfidence float64 `json:"boundary_confidence"`
+}
+
+// boundaryConfidence scores the boundary after a sentence. Terminal punctuation
+// contributes 0.6, and the rest comes from the pause before the next sentence,
+// reaching the full 0.4 at sentencePauseSeconds. The end of an utterance counts
+// as a full pause, since the speaker stops there.
+func boundaryConfidence(text string, pause float64, utteranceEnd bool) float64 {
+	c := 0.0
+	if isSentenceEnd(text) {
+		c += 0.6
+	}
+	if utteranceEnd {
+		c += 0.4
+	} else if pause > 0 {
+		c += 0.4 * min(pause/sentencePauseSeconds, 1)
+	}
+	return roundTo(c, 2)
+}
+
+// splitSentences splits utterances into sentences with boundary confidences.
+// Pauses are measured from word timings; utterances without them have
+// interpolated sentence times, so only punctuation counts inside them.
+func splitSentences(utterances []CleanUtterance) []Sentence {
+	out := []Sentence{}
+	for _, u := range utterances {
+		var parts []CleanUtterance
+		timed := len(u.Words) > 0
+		if timed {
+			parts = sentencesFromWords(u)
+		} else {
+			parts = sentencesFromText(u)
+		}
+
+		for i, p := range parts {
+			last := i == len(parts)-1
+			pause := 0.0
+			if timed && !last {
+				pause = parts[i+1].Start - p.End
+			}
+			out = append(out, Sentence{
+				Text:               p.Text,
+				Speaker:            p.Speaker,
+				Start:              p.Start,
+				End:                p.End,
+				BoundaryConfidence: boundaryConfidence(p.Text, pause, last),
+			})
+		}
+	}
+	return out
+}
+
+// handleGetSentences returns the transcription split into sentences with boundary confidences.
+// If the transcription is not found, it returns a 404 error.
+func handleGetSentences(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(splitSentences(data))
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestBoundaryConfidence(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		pause        float64
+		utteranceEnd bool
+		want         float64
+	}{
+		{"punctuation and long pause", "Done.", 1, false, 1},
+		{"punctuation only", "Done.", 0, false, 0.6},
+		{"half pause only", "and so", 0.25, false, 0.2},
+		{"utterance end counts as a pause", "and so", 0, true, 0.4},
+		{"punctuation at utterance end", "Right?", 0, true, 1},
+		{"nothing", "and", 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := boundaryConfidence(tt.text, tt.pause, tt.utteranceEnd); got != tt.want {
+				t.Errorf("boundaryConfidence = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "Hi there. Next", Start: 0, End: 3, Words: []CleanWord{
+			{Text: "Hi", Start: 0, End: 0.5},
+			{Text: "there.", Start: 0.5, End: 1},
+			{Text: "Next", Start: 1.1, End: 3},
+		}},
+		{Speaker: "B", Text: "Untimed. Text.", Start: 3, End: 5},
+	}
+	got := splitSentences(utterances)
+
+	want := []struct {
+		text       string
+		speaker    string
+		confidence float64
+	}{
+		{"Hi there.", "A", 0.68},
+		{"Next", "A", 0.4},
+		{"Untimed.", "B", 0.6},
+		{"Text.", "B", 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sentences, want %d: %+v", len(got), len(want), got)
+	}
+	for i, s := range got {
+		if s.Text != want[i].text || s.Speaker != want[i].speaker || s.BoundaryConfidence != want[i].confidence {
+			t.Errorf("sentence %d = %+v, want %+v", i, s, want[i])
+		}
+	}
+}
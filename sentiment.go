@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// Sentiment is the sentiment of an utterance: POSITIVE, NEUTRAL, or NEGATIVE,
+// with the provider's confidence from 0 to 1.
+type Sentiment struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// sentimentSpan is the sentiment the provider detected for one sentence, in seconds.
+type sentimentSpan struct {
+	Start, End float64
+	Sentiment
+}
+
+// toSentimentSpans converts AssemblyAI's sentiment results, turning the millisecond timestamps into seconds.
+func toSentimentSpans(results []assemblyai.SentimentAnalysisResult) []sentimentSpan {
+	out := make([]sentimentSpan, len(results))
+	for i, r := range results {
+		out[i] = sentimentSpan{
+			Start: float64(assemblyai.ToInt64(r.Start)) / 1000.0,
+			End:   float64(assemblyai.ToInt64(r.End)) / 1000.0,
+			Sentiment: Sentiment{
+				Label:      string(r.Sentiment),
+				Confidence: assemblyai.ToFloat64(r.Confidence),
+			},
+		}
+	}
+	return out
+}
+
+// alignSentiments sets the Sentiment of every utterance from the spans overlapping it.
+// The label is the one covering most of the utterance, and its confidence the average
+// of that label's spans weighted by their overlap. A span running over a boundary counts
+// toward both utterances. Utterances no span overlaps keep a nil Sentiment.
+func alignSentiments(utterances []CleanUtterance, spans []sentimentSpan) {
+	for i, u := range utterances {
+		overlap := map[string]float64{}
+		weighted := map[string]float64{}
+		for _, s := range spans {
+			d := math.Min(u.End, s.End) - math.Max(u.Start, s.Start)
+			if d <= 0 {
+				continue
+			}
+			overlap[s.Label] += d
+			weighted[s.Label] += d * s.Confidence
+		}
+
+		best := ""
+		for label, d := range overlap {
+			// Ties go to the alphabetically first label, so the result does not depend on map order.
+			if best == "" || d > overlap[best] || d == overlap[best] && label < best {
+				best = label
+			}
+		}
+		if best == "" {
+			continue
+		}
+		utterances[i].Sentiment = &Sentiment{
+			Label:      best,
+			Confidence: math.Round(weighted[best]/overlap[best]*1000) / 1000,
+		}
+	}
+}
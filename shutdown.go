@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connRegistry tracks the open WebSocket connections.
+// http.Server.Shutdown does not wait for hijacked connections,
+// so shutdown waits for them here and closes the ones still open.
+type connRegistry struct {
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// wsConns holds the connections served by handleWS.
+var wsConns = &connRegistry{conns: make(map[*websocket.Conn]struct{})}
+
+// add registers an open connection.
+func (r *connRegistry) add(conn *websocket.Conn) {
+	r.mu.Lock()
+	r.conns[conn] = struct{}{}
+	r.mu.Unlock()
+	r.wg.Add(1)
+}
+
+// remove unregisters a connection once its handler is done with it.
+func (r *connRegistry) remove(conn *websocket.Conn) {
+	r.mu.Lock()
+	delete(r.conns, conn)
+	r.mu.Unlock()
+	r.wg.Done()
+}
+
+// wait blocks until every registered connection is removed or ctx ends.
+// It reports whether all connections finished.
+func (r *connRegistry) wait(ctx context.Context) bool {
+	return waitGroup(ctx, &r.wg)
+}
+
+// jobGroup tracks background work that outlives the request starting it:
+// asynchronous transcriptions and webhook deliveries.
+type jobGroup struct {
+	wg      sync.WaitGroup
+	running atomic.Int64
+}
+
+// backgroundJobs holds the background work shutdown waits for.
+var backgroundJobs = &jobGroup{}
+
+// Go runs f in a new goroutine tracked by the group.
+func (g *jobGroup) Go(f func()) {
+	g.wg.Add(1)
+	g.running.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer g.running.Add(-1)
+		f()
+	}()
+}
+
+// wait blocks until every job finished or ctx ends.
+// It reports whether all jobs finished.
+func (g *jobGroup) wait(ctx context.Context) bool {
+	return waitGroup(ctx, &g.wg)
+}
+
+// waitGroup waits for wg or for ctx to end, reporting whether wg finished.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// closeAll sends a going-away close frame to every open connection and closes it.
+// It returns the number of connections closed.
+func (r *connRegistry) closeAll() int {
+	r.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(r.conns))
+	for conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	r.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, conn := range conns {
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		conn.Close()
+	}
+	return len(conns)
+}
+
+// shutdown stops srv gracefully. HTTP requests, WebSocket connections, and
+// background jobs get until grace to finish; WebSocket connections still open
+// after that are closed with a close frame, and unfinished jobs are abandoned.
+func shutdown(srv *http.Server, grace time.Duration) {
+	slog.Info("Shutting down, waiting for in-flight requests", "grace", grace.String())
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP shutdown did not complete", "error", err)
+	} else {
+		slog.Info("HTTP requests drained")
+	}
+
+	if wsConns.wait(ctx) {
+		slog.Info("WebSocket connections finished")
+	} else {
+		slog.Warn("Closed WebSocket connections after the grace period", "connections", wsConns.closeAll())
+	}
+
+	if backgroundJobs.wait(ctx) {
+		slog.Info("Background jobs finished")
+	} else {
+		slog.Warn("Abandoned background jobs after the grace period", "jobs", backgroundJobs.running.Load())
+	}
+	slog.Info("Shutdown complete")
+}
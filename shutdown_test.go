@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJobGroupWait(t *testing.T) {
+	g := &jobGroup{}
+	release := make(chan struct{})
+	g.Go(func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if g.wait(ctx) {
+		t.Fatal("wait reported done while a job was running")
+	}
+	if got := g.running.Load(); got != 1 {
+		t.Errorf("running = %d, want 1", got)
+	}
+
+	close(release)
+	if !g.wait(context.Background()) {
+		t.Fatal("wait did not report done after the job finished")
+	}
+	if got := g.running.Load(); got != 0 {
+		t.Errorf("running = %d, want 0", got)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SpeakerDemographics are the provider's estimates for one speaker.
+// Empty fields mean the provider gave no estimate.
+type SpeakerDemographics struct {
+	Gender string
+	Age    string
+}
+
+// SpeakerAttributes is implemented by transcribers whose provider estimates
+// speaker demographics. AssemblyAI does not, so its results are always unavailable.
+type SpeakerAttributes interface {
+	// SpeakerAttributes returns the estimates of a completed transcription, keyed by speaker label.
+	SpeakerAttributes(ctx context.Context, transcriptID string) (map[string]SpeakerDemographics, error)
+}
+
+// attributeUnavailable is reported for an estimate the provider did not supply.
+const attributeUnavailable = "unavailable"
+
+// SpeakerProfile is one entry of the speakers section.
+type SpeakerProfile struct {
+	Speaker string `json:"speaker"`
+	Gender  string `json:"gender"`
+	Age     string `json:"age"`
+}
+
+// speakerProfiles lists every speaker in order of first appearance with its estimates,
+// reporting attributeUnavailable for anything attrs does not supply.
+func speakerProfiles(utterances []CleanUtterance, attrs map[string]SpeakerDemographics) []SpeakerProfile {
+	out := []SpeakerProfile{}
+	for _, label := range speakerOrder(utterances) {
+		p := SpeakerProfile{Speaker: label, Gender: attributeUnavailable, Age: attributeUnavailable}
+		if a, ok := attrs[label]; ok {
+			if a.Gender != "" {
+				p.Gender = a.Gender
+			}
+			if a.Age != "" {
+				p.Age = a.Age
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// handleGetSpeakers returns the speakers of a transcription with their demographic estimates.
+// The estimates are only collected with SPEAKER_ATTRIBUTES enabled; otherwise it returns a 404 error.
+func handleGetSpeakers(w http.ResponseWriter, r *http.Request) {
+	if !cfg.SpeakerAttributes {
+		http.Error(w, "Speaker attributes are disabled", http.StatusNotFound)
+		return
+	}
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(speakerProfiles(entry.Utterances, entry.SpeakerAttributes))
+}
+
+// errStoredFailure aborts renaming the speakers of a failed transcription.
+var errStoredFailure = errors.New("transcription failed")
+
+// errUnknownSpeakers is returned by renameSpeakers for labels absent from the transcript.
+var errUnknownSpeakers = errors.New("unknown speaker labels")
+
+// renameSpeakers returns a copy of utterances with every speaker label found in names
+// replaced by its name. It fails with errUnknownSpeakers, listing the labels in
+// sorted order, when names holds labels that no utterance has.
+func renameSpeakers(utterances []CleanUtterance, names map[string]string) ([]CleanUtterance, error) {
+	present := make(map[string]bool)
+	for _, u := range utterances {
+		present[u.Speaker] = true
+	}
+	var unknown []string
+	for label := range names {
+		if !present[label] {
+			unknown = append(unknown, label)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s", errUnknownSpeakers, strings.Join(unknown, ", "))
+	}
+
+	out := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		if name, ok := names[u.Speaker]; ok {
+			u.Speaker = name
+		}
+		out[i] = u
+	}
+	return out, nil
+}
+
+// renameSpeakerAttributes returns a copy of attrs keyed by the names that names gives
+// the speaker labels, keeping labels it does not hold. A nil attrs stays nil.
+func renameSpeakerAttributes(attrs map[string]SpeakerDemographics, names map[string]string) map[string]SpeakerDemographics {
+	if attrs == nil {
+		return nil
+	}
+	out := make(map[string]SpeakerDemographics, len(attrs))
+	for label, a := range attrs {
+		if name, ok := names[label]; ok {
+			label = name
+		}
+		out[label] = a
+	}
+	return out
+}
+
+// handleRenameSpeakers replaces speaker labels with real names in a stored transcription.
+// It expects a JSON body mapping labels to names, such as {"A": "Alice", "B": "Bob"},
+// and responds with 204. Later requests, exports included, see the new names.
+// It returns 400 for labels not in the transcript, 404 if the transcription is not found,
+// and 422 if it failed.
+func handleRenameSpeakers(w http.ResponseWriter, r *http.Request) {
+	var names map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(names) == 0 {
+		http.Error(w, "body must map at least one speaker label to a name", http.StatusBadRequest)
+		return
+	}
+	for label, name := range names {
+		if names[label] = strings.TrimSpace(name); names[label] == "" {
+			http.Error(w, "speaker names must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := mux.Vars(r)["id"]
+	failure := ""
+	found, err := updateTranscription(id, func(entry *transcriptEntry) error {
+		if entry.Status == statusFailed {
+			failure = entry.Error
+			return errStoredFailure
+		}
+		utterances, err := renameSpeakers(entry.Utterances, names)
+		if err != nil {
+			return err
+		}
+		entry.Utterances = utterances
+		entry.SpeakerAttributes = renameSpeakerAttributes(entry.SpeakerAttributes, names)
+		return nil
+	})
+	switch {
+	case !found:
+		http.Error(w, "Transcription not found", http.StatusNotFound)
+	case errors.Is(err, errUnknownSpeakers):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errStoredFailure):
+		http.Error(w, "Transcription failed: "+failure, http.StatusUnprocessableEntity)
+	case err != nil:
+		loggerFrom(r.Context()).Error("Failed to update stored transcription", "conn_id", id, "error", err)
+		http.Error(w, "Failed to update transcription", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
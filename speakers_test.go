@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// attrTranscriber is a fakeTranscriber whose provider estimates speaker demographics.
+type attrTranscriber struct {
+	fakeTranscriber
+	attrs map[string]SpeakerDemographics
+	err   error
+	calls int
+}
+
+func (a *attrTranscriber) SpeakerAttributes(ctx context.Context, transcriptID string) (map[string]SpeakerDemographics, error) {
+	a.calls++
+	return a.attrs, a.err
+}
+
+// twoSpeakers is a transcript with speakers A and B.
+var twoSpeakers = [][]Utterance{{
+	{Speaker: "A", Text: "hello", Start: 0, End: 1000},
+	{Speaker: "B", Text: "hi", Start: 1000, End: 2000},
+}}
+
+func TestCompleteTranscriptionSpeakerAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		t         Transcriber
+		wantCalls int
+		want      []SpeakerProfile
+	}{
+		{
+			"supporting provider", true,
+			&attrTranscriber{fakeTranscriber: fakeTranscriber{utterances: twoSpeakers}, attrs: map[string]SpeakerDemographics{"A": {Gender: "female", Age: "30-40"}, "B": {Age: "20-30"}}},
+			1,
+			[]SpeakerProfile{{Speaker: "A", Gender: "female", Age: "30-40"}, {Speaker: "B", Gender: attributeUnavailable, Age: "20-30"}},
+		},
+		{
+			"non-supporting provider", true,
+			&fakeTranscriber{utterances: twoSpeakers},
+			0,
+			[]SpeakerProfile{{Speaker: "A", Gender: attributeUnavailable, Age: attributeUnavailable}, {Speaker: "B", Gender: attributeUnavailable, Age: attributeUnavailable}},
+		},
+		{
+			"provider error", true,
+			&attrTranscriber{fakeTranscriber: fakeTranscriber{utterances: twoSpeakers}, err: errors.New("estimates unavailable")},
+			1,
+			[]SpeakerProfile{{Speaker: "A", Gender: attributeUnavailable, Age: attributeUnavailable}, {Speaker: "B", Gender: attributeUnavailable, Age: attributeUnavailable}},
+		},
+		{
+			"disabled", false,
+			&attrTranscriber{fakeTranscriber: fakeTranscriber{utterances: twoSpeakers}, attrs: map[string]SpeakerDemographics{"A": {Gender: "female"}}},
+			0,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestPoller(t)
+			useConfig(t, func(c *config) { c.SpeakerAttributes = tt.enabled })
+			const id = "speaker-attrs"
+			t.Cleanup(func() { deleteTranscription(id) })
+
+			entry, _, err := completeTranscription(context.Background(), tt.t, "transcript-1", id, &assemblyai.TranscriptOptionalParams{}, ingestOptions{}, nil)
+			if err != nil {
+				t.Fatalf("err = %v, want the transcript stored", err)
+			}
+			if len(entry.Utterances) != 2 {
+				t.Errorf("stored %d utterances, want 2", len(entry.Utterances))
+			}
+			if at, ok := tt.t.(*attrTranscriber); ok {
+				if at.calls != tt.wantCalls {
+					t.Errorf("SpeakerAttributes called %d times, want %d", at.calls, tt.wantCalls)
+				}
+				if at.err != nil && entry.SpeakerAttributes != nil {
+					t.Errorf("stored attributes %v after an error, want none", entry.SpeakerAttributes)
+				}
+			}
+
+			w := serveTranscription(handleGetSpeakers, id, "")
+			if tt.want == nil {
+				if w.Code != http.StatusNotFound {
+					t.Errorf("GET speakers = %d, want 404", w.Code)
+				}
+				return
+			}
+			var got []SpeakerProfile
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("GET speakers = %d: %v", w.Code, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("speakers = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
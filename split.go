@@ -0,0 +1,117 @@
+package main
+
+import "strings"
+
+// isSentenceEnd reports whether a word or text fragment ends a sentence.
+func isSentenceEnd(s string) bool {
+	s = strings.TrimRight(s, `"')`)
+	return strings.HasSuffix(s, ".") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!")
+}
+
+// sentencesFromWords splits an utterance into one piece per sentence using its word timings.
+func sentencesFromWords(u CleanUtterance) []CleanUtterance {
+	var out []CleanUtterance
+	var words []CleanWord
+	flush := func() {
+		if len(words) == 0 {
+			return
+		}
+		texts := make([]string, len(words))
+		for i, w := range words {
+			texts[i] = w.Text
+		}
+		out = append(out, CleanUtterance{
+			Text:       strings.Join(texts, " "),
+			Speaker:    u.Speaker,
+			Start:      words[0].Start,
+			End:        words[len(words)-1].End,
+			Confidence: u.Confidence,
+			Words:      words,
+		})
+		words = nil
+	}
+	for _, w := range u.Words {
+		words = append(words, w)
+		if isSentenceEnd(w.Text) {
+			flush()
+		}
+	}
+	flush()
+	return out
+}
+
+// sentencesFromText splits an utterance into one piece per sentence of its text.
+// Without word timings, each sentence's times are interpolated from its share of the characters.
+func sentencesFromText(u CleanUtterance) []CleanUtterance {
+	var sentences []string
+	var current []string
+	for _, f := range strings.Fields(u.Text) {
+		current = append(current, f)
+		if isSentenceEnd(f) {
+			sentences = append(sentences, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		sentences = append(sentences, strings.Join(current, " "))
+	}
+
+	total := 0
+	for _, s := range sentences {
+		total += len(s)
+	}
+	if total == 0 {
+		return []CleanUtterance{u}
+	}
+
+	out := make([]CleanUtterance, len(sentences))
+	duration := u.End - u.Start
+	offset := 0
+	for i, s := range sentences {
+		start := u.Start + duration*float64(offset)/float64(total)
+		offset += len(s)
+		end := u.Start + duration*float64(offset)/float64(total)
+		out[i] = CleanUtterance{Text: s, Speaker: u.Speaker, Start: start, End: end, Confidence: u.Confidence}
+	}
+	return out
+}
+
+// splitLongUtterances splits utterances longer than maxDuration seconds at sentence boundaries.
+// Sentences are packed greedily into parts no longer than maxDuration, except when a
+// single sentence is longer by itself. Timings come from the words when available and
+// are interpolated from the text otherwise. The input slice is not modified.
+func splitLongUtterances(utterances []CleanUtterance, maxDuration float64) []CleanUtterance {
+	out := make([]CleanUtterance, 0, len(utterances))
+	for _, u := range utterances {
+		if u.End-u.Start <= maxDuration {
+			out = append(out, u)
+			continue
+		}
+
+		var sentences []CleanUtterance
+		if len(u.Words) > 0 {
+			sentences = sentencesFromWords(u)
+		} else {
+			sentences = sentencesFromText(u)
+		}
+
+		var part *CleanUtterance
+		for _, s := range sentences {
+			if part != nil && s.End-part.Start <= maxDuration {
+				part.Text += " " + s.Text
+				part.End = s.End
+				part.Words = append(part.Words, s.Words...)
+				continue
+			}
+			if part != nil {
+				out = append(out, *part)
+			}
+			s := s
+			part = &s
+		}
+		if part != nil {
+			out = append(out, *part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsSentenceEnd(t *testing.T) {
+	tests := map[string]bool{
+		"done.":     true,
+		"really?":   true,
+		"wow!":      true,
+		`"quoted."`: true,
+		"(aside.)":  true,
+		"comma,":    false,
+		"word":      false,
+		"":          false,
+	}
+	for s, want := range tests {
+		if got := isSentenceEnd(s); got != want {
+			t.Errorf("isSentenceEnd(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestSentencesFromText(t *testing.T) {
+	u := CleanUtterance{Speaker: "A", Text: "One two. Three four five six.", Start: 10, End: 20, Confidence: 0.9}
+	got := sentencesFromText(u)
+
+	if len(got) != 2 || got[0].Text != "One two." || got[1].Text != "Three four five six." {
+		t.Fatalf("sentences = %+v", got)
+	}
+	if got[0].Start != 10 || got[1].End != 20 || got[0].End != got[1].Start {
+		t.Errorf("times = %v-%v, %v-%v, want contiguous from 10 to 20", got[0].Start, got[0].End, got[1].Start, got[1].End)
+	}
+	// The first sentence has 8 of the 28 characters.
+	if want := 10 + 10*8.0/28; got[0].End != want {
+		t.Errorf("first end = %v, want %v", got[0].End, want)
+	}
+	if got[1].Speaker != "A" || got[1].Confidence != 0.9 {
+		t.Errorf("speaker and confidence not kept: %+v", got[1])
+	}
+
+	if got := sentencesFromText(CleanUtterance{Text: "   ", Start: 1, End: 2}); len(got) != 1 || got[0].Start != 1 {
+		t.Errorf("blank text: got %+v, want the utterance unchanged", got)
+	}
+}
+
+func TestSplitLongUtterances(t *testing.T) {
+	words := []CleanWord{
+		{Text: "First.", Start: 0, End: 4},
+		{Text: "Second", Start: 5, End: 8},
+		{Text: "part.", Start: 8, End: 12},
+		{Text: "Third.", Start: 13, End: 30},
+	}
+	timed := CleanUtterance{Speaker: "A", Text: "First. Second part. Third.", Start: 0, End: 30, Words: words}
+	short := CleanUtterance{Speaker: "B", Text: "Short. Reply.", Start: 30, End: 32}
+	input := []CleanUtterance{timed, short}
+
+	got := splitLongUtterances(input, 15)
+
+	wantTexts := []string{"First. Second part.", "Third.", "Short. Reply."}
+	var texts []string
+	for _, u := range got {
+		texts = append(texts, u.Text)
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Fatalf("texts = %q, want %q", texts, wantTexts)
+	}
+	if got[0].Start != 0 || got[0].End != 12 || len(got[0].Words) != 3 {
+		t.Errorf("first part = %v-%v with %d words, want 0-12 with 3", got[0].Start, got[0].End, len(got[0].Words))
+	}
+	if got[1].Start != 13 || got[1].End != 30 {
+		t.Errorf("a sentence longer than the limit is kept whole: got %v-%v", got[1].Start, got[1].End)
+	}
+	if input[0].Text != timed.Text || len(input[0].Words) != 4 {
+		t.Error("input was modified")
+	}
+
+	untimed := CleanUtterance{Text: "Aaaa. Bbbb.", Start: 0, End: 20}
+	if got := splitLongUtterances([]CleanUtterance{untimed}, 10); len(got) != 2 || got[1].Start != 10 {
+		t.Errorf("untimed split = %+v, want two halves", got)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Transcription statuses reported by the status endpoints.
+const (
+	statusProcessing = "processing"
+	statusCompleted  = "completed"
+	statusFailed     = "failed"
+	statusNotFound   = "not_found"
+)
+
+// maxStatusIDs caps the number of IDs accepted by a single bulk status request.
+const maxStatusIDs = 100
+
+// defaultListLimit and maxListLimit bound the page size of the transcription listing.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// lookupStatuses returns the status of each of the given connection IDs.
+// Stored transcriptions are read under one lock; the rest are looked up in the in-flight registry.
+func lookupStatuses(ids []string) map[string]string {
+	statuses := make(map[string]string, len(ids))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, id := range ids {
+		if entry, ok := transcriptions[id]; ok {
+			statuses[id] = entry.Status
+		} else if job, ok := inflight.Get(id); ok {
+			statuses[id] = job.Status
+		} else {
+			statuses[id] = statusNotFound
+		}
+	}
+	return statuses
+}
+
+// handleBulkStatus reports the status of several transcriptions at once.
+// It expects a JSON body of the form {"ids": ["...", "..."]}
+// and responds with a JSON object mapping each ID to its status.
+func handleBulkStatus(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if len(body.IDs) == 0 {
+		http.Error(w, "ids must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(body.IDs) > maxStatusIDs {
+		http.Error(w, fmt.Sprintf("at most %d ids per request", maxStatusIDs), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lookupStatuses(body.IDs))
+}
+
+// handleCancel cancels a transcription that is still in progress.
+// It returns 202 once cancellation is requested, or 404 if the ID is not in flight.
+func handleCancel(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if !inflight.Cancel(id) {
+		http.Error(w, "Transcription not in progress", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleDeleteTranscription removes a stored transcription.
+// It returns 204 once removed, or 404 if the ID is not stored.
+func handleDeleteTranscription(w http.ResponseWriter, r *http.Request) {
+	if !deleteTranscription(mux.Vars(r)["id"]) {
+		http.Error(w, "Transcription not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListTranscriptions lists the stored transcriptions, oldest first.
+// The optional ?limit (default 100, at most 1000) and ?offset query
+// parameters page through the list.
+func handleListTranscriptions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultListLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxListLimit {
+			http.Error(w, fmt.Sprintf("limit must be an integer between 1 and %d", maxListLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	offset := 0
+	if o := r.URL.Query().Get("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listTranscriptions(offset, limit))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// postStatuses calls handleBulkStatus with body and returns the recorded response.
+func postStatuses(body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handleBulkStatus(w, httptest.NewRequest("POST", "/statuses", strings.NewReader(body)))
+	return w
+}
+
+func TestHandleBulkStatus(t *testing.T) {
+	storeTestTranscription(t, "status-done", []CleanUtterance{{Text: "hi"}})
+	saveFailure("status-failed", "boom")
+	t.Cleanup(func() { deleteTranscription("status-failed") })
+	inflight.Add("status-running", statusProcessing, func() {})
+	t.Cleanup(func() { inflight.Remove("status-running") })
+
+	w := postStatuses(`{"ids": ["status-done", "status-failed", "status-running", "status-unknown"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"status-done":    statusCompleted,
+		"status-failed":  statusFailed,
+		"status-running": statusProcessing,
+		"status-unknown": statusNotFound,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestHandleBulkStatusRejectsBadRequests(t *testing.T) {
+	tooMany := make([]string, maxStatusIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("id-%d", i)
+	}
+	tooManyBody, _ := json.Marshal(map[string][]string{"ids": tooMany})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty ids", `{"ids": []}`},
+		{"missing ids", `{}`},
+		{"too many ids", string(tooManyBody)},
+		{"bad json", `{"ids": [`},
+	}
+	for _, tt := range tests {
+		if w := postStatuses(tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tt.name, w.Code)
+		}
+	}
+
+	exactly := tooMany[:maxStatusIDs]
+	body, _ := json.Marshal(map[string][]string{"ids": exactly})
+	if w := postStatuses(string(body)); w.Code != http.StatusOK {
+		t.Errorf("%d ids: status = %d, want 200", maxStatusIDs, w.Code)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// transcriptEntry is a stored transcription.
+// When compression is enabled, the utterances are kept as gzipped JSON
+// in Compressed and Utterances is nil.
+// A failed transcription has Status set to statusFailed and a descriptive Error.
+type transcriptEntry struct {
+	Status     string
+	Error      string
+	Utterances []CleanUtterance
+	Compressed []byte
+	// CostCenter is the caller-supplied chargeback tag, if any.
+	CostCenter string
+	// Provider is the Name of the Transcriber that produced the result.
+	Provider string
+	// Language is the language code the audio was transcribed in.
+	Language string
+	// Topics is the topic detection result, or nil if it was not requested.
+	Topics *TopicReport
+	// Chapters are the detected chapters, or nil if they were not requested.
+	Chapters []Chapter
+	// SpeakerAttributes are the provider's speaker estimates, only collected with SPEAKER_ATTRIBUTES.
+	SpeakerAttributes map[string]SpeakerDemographics
+	// Summary is the LeMUR summary, or nil if it was not requested or failed.
+	Summary *MeetingSummary
+	// Translations holds the translated text of each utterance, in order, by target language.
+	Translations map[string][]string
+	// SectionSummaries holds the one-line summaries of chapters and segments, by sectionKey.
+	SectionSummaries map[string]string
+	// CreatedAt is when the entry was stored, or the client's timestamp
+	// with CREATED_AT_SOURCE=client.
+	CreatedAt time.Time
+	// UtteranceCount is the number of utterances, kept so listings
+	// don't need to decompress the entry.
+	UtteranceCount int
+}
+
+// Global map to store transcriptions keyed by connection ID.
+// This is used to retrieve transcriptions later.
+// byCreated indexes the same entries in listing order, so listings don't
+// have to sort the map; it is maintained under mu with the map.
+var (
+	transcriptions = make(map[string]transcriptEntry)
+	byCreated      []indexKey
+	mu             sync.Mutex
+	// lastStamped is the last timestamp handed out by serverTimestamp.
+	lastStamped time.Time
+	// storeClock is the clock serverTimestamp reads.
+	storeClock Clock = realClock{}
+)
+
+// serverTimestamp returns the creation time of an entry stored now. It never
+// repeats or goes backwards, even if the wall clock is stepped back, so on one
+// instance entries list in the order they were stored.
+// mu must be held.
+func serverTimestamp() time.Time {
+	now := storeClock.Now().Round(0)
+	if !now.After(lastStamped) {
+		now = lastStamped.Add(time.Nanosecond)
+	}
+	lastStamped = now
+	return now
+}
+
+// indexKey is the position of an entry in the byCreated index.
+type indexKey struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// before orders keys by creation time, then by ID so entries stored at the same instant have a stable order.
+func (k indexKey) before(o indexKey) bool {
+	if !k.CreatedAt.Equal(o.CreatedAt) {
+		return k.CreatedAt.Before(o.CreatedAt)
+	}
+	return k.ID < o.ID
+}
+
+// putEntry stores entry under id, replacing any previous entry and keeping byCreated in order.
+// mu must be held.
+func putEntry(id string, entry transcriptEntry) {
+	removeEntry(id)
+	transcriptions[id] = entry
+	key := indexKey{CreatedAt: entry.CreatedAt, ID: id}
+	i := sort.Search(len(byCreated), func(i int) bool { return !byCreated[i].before(key) })
+	byCreated = slices.Insert(byCreated, i, key)
+}
+
+// removeEntry deletes the entry under id from the map and the index, reporting whether it existed.
+// mu must be held.
+func removeEntry(id string) bool {
+	entry, ok := transcriptions[id]
+	if !ok {
+		return false
+	}
+	delete(transcriptions, id)
+	key := indexKey{CreatedAt: entry.CreatedAt, ID: id}
+	i := sort.Search(len(byCreated), func(i int) bool { return !byCreated[i].before(key) })
+	if i < len(byCreated) && byCreated[i].ID == id {
+		byCreated = slices.Delete(byCreated, i, i+1)
+	}
+	return true
+}
+
+// compressUtterances serializes utterances to JSON and gzips the result.
+func compressUtterances(utterances []CleanUtterance) ([]byte, int, error) {
+	raw, err := json.Marshal(utterances)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(raw), nil
+}
+
+// decompressUtterances reverses compressUtterances.
+func decompressUtterances(data []byte) ([]CleanUtterance, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	var utterances []CleanUtterance
+	if err := json.Unmarshal(raw, &utterances); err != nil {
+		return nil, err
+	}
+	return utterances, nil
+}
+
+// saveTranscription stores a completed transcription under the given connection ID.
+// Unless entry carries a client CreatedAt, it is stamped with serverTimestamp.
+// With STORE_COMPRESS enabled, the utterances are stored gzipped and the compression ratio is logged.
+// If compression fails, the utterances are stored uncompressed.
+func saveTranscription(id string, entry transcriptEntry) {
+	entry.Status = statusCompleted
+	entry.UtteranceCount = len(entry.Utterances)
+
+	if cfg.StoreCompress {
+		compressed, rawSize, err := compressUtterances(entry.Utterances)
+		if err != nil {
+			slog.Warn("Compression failed, storing uncompressed", "conn_id", id, "error", err)
+		} else {
+			entry.Utterances = nil
+			entry.Compressed = compressed
+			slog.Debug("Stored compressed", "conn_id", id, "raw_bytes", rawSize,
+				"compressed_bytes", len(compressed), "ratio", float64(len(compressed))/float64(rawSize))
+		}
+	}
+
+	mu.Lock()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = serverTimestamp()
+	}
+	putEntry(id, entry)
+	mu.Unlock()
+	exports.invalidate(id)
+}
+
+// saveFailure records that the transcription under the given connection ID failed.
+// The reason is kept so the GET endpoint can report it.
+func saveFailure(id, reason string) {
+	mu.Lock()
+	putEntry(id, transcriptEntry{Status: statusFailed, Error: reason, CreatedAt: serverTimestamp()})
+	mu.Unlock()
+	exports.invalidate(id)
+}
+
+// deleteTranscription removes the entry stored under the given connection ID,
+// along with its cached exports. It reports whether the ID existed.
+func deleteTranscription(id string) bool {
+	mu.Lock()
+	ok := removeEntry(id)
+	mu.Unlock()
+
+	if ok {
+		exports.forget(id)
+	}
+	return ok
+}
+
+// expireTranscriptions removes the entries created more than ttl before now,
+// along with their cached exports, and returns how many were removed.
+// The expired entries are the oldest, so only they are visited; exports are
+// invalidated after releasing the lock.
+func expireTranscriptions(ttl time.Duration, now time.Time) int {
+	mu.Lock()
+	n := 0
+	for n < len(byCreated) && now.Sub(byCreated[n].CreatedAt) > ttl {
+		n++
+	}
+	expired := make([]string, n)
+	for i, key := range byCreated[:n] {
+		delete(transcriptions, key.ID)
+		expired[i] = key.ID
+	}
+	byCreated = slices.Delete(byCreated, 0, n)
+	mu.Unlock()
+
+	for _, id := range expired {
+		exports.forget(id)
+	}
+	return n
+}
+
+// idsCreatedBefore returns the IDs of the entries created before t, oldest first.
+func idsCreatedBefore(t time.Time) []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var ids []string
+	for _, key := range byCreated {
+		if !key.CreatedAt.Before(t) {
+			break
+		}
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
+
+// runStoreSweeper removes expired transcriptions every interval until ctx is cancelled.
+func runStoreSweeper(ctx context.Context, ttl, interval time.Duration, clock Clock) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+			if n := expireTranscriptions(ttl, clock.Now()); n > 0 {
+				slog.Info("Removed expired transcriptions", "count", n)
+			}
+		}
+	}
+}
+
+// getTranscription returns the entry stored under the given connection ID,
+// with its utterances decompressed if needed. The bool reports whether the ID exists.
+func getTranscription(id string) (transcriptEntry, bool, error) {
+	mu.Lock()
+	entry, ok := transcriptions[id]
+	mu.Unlock()
+
+	if !ok || entry.Compressed == nil {
+		return entry, ok, nil
+	}
+
+	utterances, err := decompressUtterances(entry.Compressed)
+	if err != nil {
+		return entry, true, err
+	}
+	entry.Utterances = utterances
+	entry.Compressed = nil
+	return entry, true, nil
+}
+
+// updateTranscription applies update to the entry stored under the given connection ID
+// and stores the result, invalidating its cached exports. update sees the utterances
+// decompressed, and they are compressed again if they were stored compressed.
+// The entry is locked throughout, so concurrent updates don't overwrite each other.
+// If update returns an error, the entry is left unchanged and the error is returned.
+// The bool reports whether the ID exists.
+func updateTranscription(id string, update func(*transcriptEntry) error) (bool, error) {
+	// Deferred first, so the exports are invalidated after mu is released.
+	stored := false
+	defer func() {
+		if stored {
+			exports.invalidate(id)
+		}
+	}()
+	mu.Lock()
+	defer mu.Unlock()
+
+	entry, ok := transcriptions[id]
+	if !ok {
+		return false, nil
+	}
+	compressed := entry.Compressed != nil
+	if compressed {
+		utterances, err := decompressUtterances(entry.Compressed)
+		if err != nil {
+			return true, err
+		}
+		entry.Utterances = utterances
+		entry.Compressed = nil
+	}
+
+	if err := update(&entry); err != nil {
+		return true, err
+	}
+	entry.UtteranceCount = len(entry.Utterances)
+
+	if compressed {
+		data, _, err := compressUtterances(entry.Utterances)
+		if err != nil {
+			return true, err
+		}
+		entry.Utterances = nil
+		entry.Compressed = data
+	}
+	putEntry(id, entry)
+	stored = true
+	return true, nil
+}
+
+// storedTranscription is one item of the transcription listing.
+type storedTranscription struct {
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UtteranceCount int       `json:"utterance_count"`
+	Provider       string    `json:"provider,omitempty"`
+	CostCenter     string    `json:"cost_center,omitempty"`
+}
+
+// listTranscriptions returns the stored transcriptions ordered by creation time,
+// oldest first, skipping offset entries and returning at most limit.
+// Entries stored at the same instant are ordered by ID so pages are stable.
+// With server timestamps that is the storing order of this instance; see serverTimestamp.
+// The page is read from the byCreated index, so only its entries are visited.
+func listTranscriptions(offset, limit int) []storedTranscription {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if offset >= len(byCreated) {
+		return []storedTranscription{}
+	}
+	page := byCreated[offset:]
+	if limit < len(page) {
+		page = page[:limit]
+	}
+
+	out := make([]storedTranscription, len(page))
+	for i, key := range page {
+		entry := transcriptions[key.ID]
+		out[i] = storedTranscription{ID: key.ID, CreatedAt: key.CreatedAt, UtteranceCount: entry.UtteranceCount, Provider: entry.Provider, CostCenter: entry.CostCenter}
+	}
+	return out
+}
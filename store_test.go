@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// richUtterances has every field compression must preserve.
+var richUtterances = []CleanUtterance{
+	{
+		Text: "Good morning", OriginalText: "Buenos días", Speaker: "A", Start: 0.5, End: 1.75, Confidence: 0.93,
+		Words:     []CleanWord{{Text: "Good", Start: 0.5, End: 0.9, Confidence: 0.95}, {Text: "morning", Start: 1, End: 1.75, Confidence: 0.91}},
+		Sentiment: &Sentiment{Label: "POSITIVE", Confidence: 0.88},
+	},
+	{Text: "hmm", Speaker: "B", Start: 2, End: 2.2, Confidence: 0.3, LowConfidence: true},
+}
+
+func TestCompressUtterancesRoundTrip(t *testing.T) {
+	compressed, rawSize, err := compressUtterances(richUtterances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawSize == 0 || len(compressed) == 0 {
+		t.Fatalf("raw size %d, compressed %d bytes, want both non-zero", rawSize, len(compressed))
+	}
+
+	got, err := decompressUtterances(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, richUtterances) {
+		t.Errorf("round trip =\n%+v\nwant\n%+v", got, richUtterances)
+	}
+}
+
+func TestSaveTranscriptionCompressed(t *testing.T) {
+	useConfig(t, func(c *config) { c.StoreCompress = true })
+	storeTestTranscription(t, "compressed-1", richUtterances)
+
+	mu.Lock()
+	raw := transcriptions["compressed-1"]
+	mu.Unlock()
+	if raw.Utterances != nil || raw.Compressed == nil {
+		t.Fatalf("stored utterances %v, compressed %d bytes, want only the compressed form", raw.Utterances, len(raw.Compressed))
+	}
+
+	entry, ok, err := getTranscription("compressed-1")
+	if !ok || err != nil {
+		t.Fatalf("getTranscription = %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(entry.Utterances, richUtterances) || entry.UtteranceCount != len(richUtterances) {
+		t.Errorf("read back %+v (count %d), want %+v", entry.Utterances, entry.UtteranceCount, richUtterances)
+	}
+}
+
+func TestDecompressUtterancesRejectsGarbage(t *testing.T) {
+	if _, err := decompressUtterances([]byte("not gzip")); err == nil {
+		t.Error("decompressing garbage succeeded")
+	}
+}
+
+// useEmptyStore swaps in an empty transcription store for the rest of the test.
+func useEmptyStore(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	prevMap, prevIndex := transcriptions, byCreated
+	transcriptions, byCreated = make(map[string]transcriptEntry), nil
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		transcriptions, byCreated = prevMap, prevIndex
+		mu.Unlock()
+	})
+}
+
+// listedIDs returns the IDs of a listTranscriptions page.
+func listedIDs(offset, limit int) []string {
+	ids := []string{}
+	for _, st := range listTranscriptions(offset, limit) {
+		ids = append(ids, st.ID)
+	}
+	return ids
+}
+
+func TestListTranscriptionsPaging(t *testing.T) {
+	useEmptyStore(t)
+	// Stored out of order; "b" and "c" share an instant and list by ID.
+	for _, e := range []struct {
+		id  string
+		age time.Duration
+	}{{"d", 1 * time.Minute}, {"a", 4 * time.Minute}, {"c", 2 * time.Minute}, {"b", 2 * time.Minute}, {"e", 0}} {
+		saveTranscription(e.id, transcriptEntry{CreatedAt: testEpoch.Add(-e.age)})
+	}
+
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 10, []string{"a", "b", "c", "d", "e"}},
+		{0, 2, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{4, 2, []string{"e"}},
+		{5, 2, []string{}},
+		{9, 2, []string{}},
+		{1, 0, []string{}},
+	}
+	for _, tt := range tests {
+		if got := listedIDs(tt.offset, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("offset %d, limit %d: got %v, want %v", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestListTranscriptionsAfterDeleteAndExpiry(t *testing.T) {
+	useEmptyStore(t)
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		saveTranscription(id, transcriptEntry{CreatedAt: testEpoch.Add(time.Duration(i) * time.Minute)})
+	}
+
+	if !deleteTranscription("c") {
+		t.Fatal("delete c: not found")
+	}
+	if got, want := listedIDs(0, 10), []string{"a", "b", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after delete: got %v, want %v", got, want)
+	}
+	if got, want := listedIDs(2, 2), []string{"d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after delete, page 2: got %v, want %v", got, want)
+	}
+
+	// Replacing an entry moves it to its new creation time.
+	saveTranscription("a", transcriptEntry{CreatedAt: testEpoch.Add(10 * time.Minute)})
+	if got, want := listedIDs(0, 10), []string{"b", "d", "e", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after replace: got %v, want %v", got, want)
+	}
+
+	// b at 1m and d at 3m are more than a 6m TTL older than 10m.
+	if n := expireTranscriptions(6*time.Minute, testEpoch.Add(10*time.Minute)); n != 2 {
+		t.Errorf("expired %d, want 2", n)
+	}
+	if got, want := listedIDs(0, 10), []string{"e", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after expiry: got %v, want %v", got, want)
+	}
+	if got, want := listedIDs(1, 10), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after expiry, offset 1: got %v, want %v", got, want)
+	}
+	if _, ok, _ := getTranscription("b"); ok {
+		t.Error("expired b is still stored")
+	}
+}
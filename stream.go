@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"github.com/gorilla/websocket"
+)
+
+// streamResult is a streaming transcript pushed to the client.
+// Partial results are superseded by the next result for the same stretch of audio,
+// so clients should overwrite them; final results are kept.
+type streamResult struct {
+	Partial    bool        `json:"partial"`
+	Text       string      `json:"text"`
+	Start      float64     `json:"start"`
+	End        float64     `json:"end"`
+	Confidence float64     `json:"confidence"`
+	Words      []CleanWord `json:"words,omitempty"`
+}
+
+// toStreamResult converts a real-time transcript, turning milliseconds into seconds.
+func toStreamResult(t assemblyai.RealTimeBaseTranscript, partial bool) streamResult {
+	words := make([]CleanWord, len(t.Words))
+	for i, w := range t.Words {
+		words[i] = CleanWord{
+			Text:       w.Text,
+			Start:      float64(w.Start) / 1000.0,
+			End:        float64(w.End) / 1000.0,
+			Confidence: w.Confidence,
+		}
+	}
+	return streamResult{
+		Partial:    partial,
+		Text:       t.Text,
+		Start:      float64(t.AudioStart) / 1000.0,
+		End:        float64(t.AudioEnd) / 1000.0,
+		Confidence: t.Confidence,
+		Words:      words,
+	}
+}
+
+// lockedConn serializes writes to a WebSocket connection,
+// which gorilla/websocket does not allow concurrently.
+type lockedConn struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// WriteJSON writes v as a JSON message.
+func (c *lockedConn) WriteJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// handleStream transcribes audio streamed over conn with AssemblyAI's real-time API.
+// Each binary message is a chunk of 16 kHz, 16-bit mono PCM audio and is forwarded as it arrives.
+// Partial and final results are pushed back as streamResult messages. A text message
+// ends the stream: the final utterances are stored under connectionID,
+// which is sent to the client as in the batch flow. ctx carries the connection's logger.
+func handleStream(ctx context.Context, conn *websocket.Conn, connectionID, apiKey string) {
+	logger := loggerFrom(ctx)
+	out := &lockedConn{conn: conn}
+
+	var (
+		finalsMu sync.Mutex
+		finals   []CleanUtterance
+	)
+	transcriber := &assemblyai.RealTimeTranscriber{
+		OnPartialTranscript: func(t assemblyai.PartialTranscript) {
+			if t.Text == "" {
+				return
+			}
+			out.WriteJSON(toStreamResult(t.RealTimeBaseTranscript, true))
+		},
+		OnFinalTranscript: func(t assemblyai.FinalTranscript) {
+			res := toStreamResult(t.RealTimeBaseTranscript, false)
+			finalsMu.Lock()
+			finals = append(finals, CleanUtterance{Text: res.Text, Start: res.Start, End: res.End, Confidence: res.Confidence, Words: res.Words})
+			finalsMu.Unlock()
+			out.WriteJSON(res)
+		},
+		OnError: func(err error) {
+			logger.Error("Streaming error", "error", err)
+		},
+	}
+	client := assemblyai.NewRealTimeClientWithOptions(
+		assemblyai.WithRealTimeAPIKey(apiKey),
+		assemblyai.WithRealTimeTranscriber(transcriber),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	inflight.Add(connectionID, statusProcessing, cancel)
+	defer inflight.Remove(connectionID)
+
+	if err := client.Connect(ctx); err != nil {
+		logger.Error("Streaming connect failed", "error", err)
+		out.WriteJSON(wsError{Error: "transcription failed", Code: errCodeStream, Detail: err.Error(), Retryable: true})
+		return
+	}
+
+	// Frames are read through readChunk rather than ReadMessage, so neither one
+	// giant frame nor a long stream can take the audio past MAX_AUDIO_BYTES.
+	received := 0
+	for {
+		mt, r, err := conn.NextReader()
+		if err != nil {
+			logger.Info("Stream closed by client", "error", err)
+			client.Disconnect(ctx, false)
+			return
+		}
+		if mt != websocket.BinaryMessage {
+			break
+		}
+		data, err := readChunk(r, received, cfg.MaxAudioBytes)
+		if errors.Is(err, errAudioTooLarge) {
+			logger.Warn("Stopped oversized stream", "limit_bytes", cfg.MaxAudioBytes)
+			client.Disconnect(ctx, false)
+			out.WriteJSON(wsError{Error: "transcription failed", Code: errCodeAudioTooLarge, Detail: err.Error()})
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseMessageTooBig, "audio too large"), time.Now().Add(time.Second))
+			return
+		}
+		if err != nil {
+			logger.Info("Stream closed by client", "error", err)
+			client.Disconnect(ctx, false)
+			return
+		}
+		received += len(data)
+		if err := client.Send(ctx, data); err != nil {
+			logger.Error("Streaming send failed", "error", err)
+			client.Disconnect(ctx, false)
+			out.WriteJSON(wsError{Error: "transcription failed", Code: errCodeStream, Detail: err.Error(), Retryable: true})
+			return
+		}
+	}
+
+	// Waiting for the session to terminate flushes the remaining final transcripts.
+	if err := client.Disconnect(ctx, true); err != nil {
+		logger.Warn("Streaming disconnect failed", "error", err)
+	}
+
+	finalsMu.Lock()
+	utterances := finals
+	finalsMu.Unlock()
+	if utterances == nil {
+		utterances = []CleanUtterance{}
+	}
+	saveTranscription(connectionID, transcriptEntry{Utterances: utterances})
+
+	out.WriteJSON(map[string]string{"connection_id": connectionID})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MeetingSummary is the summary of a transcription.
+// The summary comes from LeMUR, or from AssemblyAI summarization of the
+// chosen Type and Model when summary_type or summary_model was given.
+// Action items always come from LeMUR.
+// Source is set when the summary is served: summarySourceProvider, or
+// summarySourceFallback for an extractive summary built by this server.
+type MeetingSummary struct {
+	Summary     string   `json:"summary"`
+	ActionItems []string `json:"action_items"`
+	Type        string   `json:"summary_type,omitempty"`
+	Model       string   `json:"summary_model,omitempty"`
+	Source      string   `json:"source,omitempty"`
+}
+
+// Sources of a served MeetingSummary.
+const (
+	summarySourceProvider = "provider"
+	summarySourceFallback = "fallback"
+)
+
+// extractiveSummary builds a fallback summary from the first, the longest, and
+// the last utterance, in transcript order and without repeats. It has no action items.
+func extractiveSummary(utterances []CleanUtterance) MeetingSummary {
+	summary := MeetingSummary{ActionItems: []string{}, Source: summarySourceFallback}
+	if len(utterances) == 0 {
+		return summary
+	}
+
+	longest := 0
+	for i, u := range utterances {
+		if len(u.Text) > len(utterances[longest].Text) {
+			longest = i
+		}
+	}
+	picks := []int{0, longest, len(utterances) - 1}
+	sort.Ints(picks)
+
+	var parts []string
+	for i, p := range picks {
+		if i > 0 && p == picks[i-1] {
+			continue
+		}
+		if text := strings.TrimSpace(utterances[p].Text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	summary.Summary = strings.Join(parts, " ")
+	return summary
+}
+
+// parseBulletList splits a bullet point answer into its items,
+// stripping list markers such as "-", "*", "•", or "1.".
+func parseBulletList(text string) []string {
+	items := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "-*•")
+		if i := strings.IndexAny(line, ".)"); i > 0 && strings.Trim(line[:i], "0123456789") == "" {
+			line = line[i+1:]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items
+}
+
+// handleGetSummary returns the summary and action items of a transcription.
+// Summaries are only generated for uploads with ?summarize=true, ?summary_type,
+// or ?summary_model; otherwise, or if summarization failed, it returns a 404 error,
+// or an extractive summary when SUMMARY_FALLBACK is enabled.
+func handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return
+	}
+
+	var summary MeetingSummary
+	switch {
+	case entry.Summary != nil:
+		summary = *entry.Summary
+		summary.Source = summarySourceProvider
+	case cfg.SummaryFallback:
+		summary = extractiveSummary(entry.Utterances)
+	default:
+		http.Error(w, "Summary not available for this transcription", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// SectionSummarizer is implemented by transcribers that can summarize arbitrary
+// text with an LLM, used for the one-line summaries of chapters and segments.
+type SectionSummarizer interface {
+	// SummarizeSection returns a one-sentence summary of text.
+	SummarizeSection(ctx context.Context, text string) (string, error)
+}
+
+// newSectionSummarizer returns the SectionSummarizer for the given API key.
+// It is a variable so the section summaries can run against a fake LLM.
+var newSectionSummarizer = func(apiKey string) SectionSummarizer {
+	return newAssemblyAITranscriber(apiKey, "")
+}
+
+// summarizeSections summarizes every non-empty text with s, one call each, in order.
+// Empty texts get an empty summary. It stops at the first failure, including ctx
+// ending, and returns the summaries made so far, empty for the rest, with the error.
+func summarizeSections(ctx context.Context, s SectionSummarizer, texts []string) ([]string, error) {
+	summaries := make([]string, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		summary, err := s.SummarizeSection(ctx, text)
+		if err != nil {
+			return summaries, err
+		}
+		summaries[i] = strings.TrimSpace(summary)
+	}
+	return summaries, nil
+}
+
+// sectionKey identifies a section by its text, so its summary is reused by any
+// endpoint or window that yields the same section.
+func sectionKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// sectionSummaries handles ?summaries=true for the chapters and segments endpoints
+// of the transcription id: it summarizes texts within SECTION_SUMMARY_TIMEOUT.
+// Summaries already stored on entry are reused, and new ones are stored, so a section
+// is only billed once. Summaries that fail or run out of time are left empty and logged,
+// so the sections are still served. It writes an error and returns false when summaries
+// are disabled, there are more than MAX_SUMMARY_SECTIONS sections with text, or the
+// provider is not configured.
+func sectionSummaries(w http.ResponseWriter, r *http.Request, id string, entry transcriptEntry, texts []string) ([]string, bool) {
+	if !cfg.SectionSummaries {
+		http.Error(w, "summaries are disabled on this server", http.StatusBadRequest)
+		return nil, false
+	}
+
+	summaries := make([]string, len(texts))
+	missing := make([]string, len(texts))
+	sections, uncached := 0, 0
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		sections++
+		if summary, ok := entry.SectionSummaries[sectionKey(text)]; ok {
+			summaries[i] = summary
+			continue
+		}
+		missing[i] = text
+		uncached++
+	}
+	if sections > cfg.MaxSummarySections {
+		http.Error(w, fmt.Sprintf("summaries: %d sections, at most %d can be summarized", sections, cfg.MaxSummarySections), http.StatusBadRequest)
+		return nil, false
+	}
+	if uncached == 0 {
+		return summaries, true
+	}
+
+	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
+	if apiKey == "" {
+		loggerFrom(r.Context()).Error("API key not found in environment")
+		http.Error(w, "Transcription provider is not configured", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.SectionSummaryTimeout)
+	defer cancel()
+	made, err := summarizeSections(ctx, newSectionSummarizer(apiKey), missing)
+	if err != nil {
+		loggerFrom(r.Context()).Warn("Section summaries incomplete", "error", err)
+	}
+
+	fresh := make(map[string]string)
+	for i, summary := range made {
+		if summary != "" {
+			summaries[i] = summary
+			fresh[sectionKey(missing[i])] = summary
+		}
+	}
+	if len(fresh) > 0 {
+		if _, err := updateTranscription(id, func(e *transcriptEntry) error {
+			storeSectionSummaries(e, fresh)
+			return nil
+		}); err != nil {
+			loggerFrom(r.Context()).Warn("Failed to cache section summaries", "conn_id", id, "error", err)
+		}
+	}
+	return summaries, true
+}
+
+// storeSectionSummaries adds summaries, keyed by sectionKey, to those kept on entry.
+// Like storeTranslation, it replaces the map instead of modifying it.
+func storeSectionSummaries(entry *transcriptEntry, summaries map[string]string) {
+	merged := maps.Clone(entry.SectionSummaries)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	maps.Copy(merged, summaries)
+	entry.SectionSummaries = merged
+}
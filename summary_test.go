@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSummarizer summarizes a section as "summary of <text>", counting its calls.
+type fakeSummarizer struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeSummarizer) SummarizeSection(ctx context.Context, text string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return "summary of " + text, nil
+}
+
+// useFakeSummarizer makes section summaries use f for the rest of the test.
+func useFakeSummarizer(t *testing.T, f *fakeSummarizer) {
+	t.Helper()
+	t.Setenv("ASSEMBLYAI_API_KEY", "test-key")
+	restore := newSectionSummarizer
+	newSectionSummarizer = func(string) SectionSummarizer { return f }
+	t.Cleanup(func() { newSectionSummarizer = restore })
+}
+
+func TestSectionSummariesCachedOnEntry(t *testing.T) {
+	useConfig(t, func(c *config) {
+		c.SectionSummaries = true
+		c.MaxSummarySections = 50
+		c.SectionSummaryTimeout = time.Minute
+	})
+	f := &fakeSummarizer{}
+	useFakeSummarizer(t, f)
+
+	saveTranscription("sections-1", transcriptEntry{Utterances: []CleanUtterance{{Text: "hi"}}})
+	t.Cleanup(func() { deleteTranscription("sections-1") })
+
+	texts := []string{"first part", "", "second part"}
+	for round := 1; round <= 2; round++ {
+		entry, _, _ := getTranscription("sections-1")
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		summaries, ok := sectionSummaries(w, r, "sections-1", entry, texts)
+		if !ok {
+			t.Fatalf("round %d: status %d: %s", round, w.Code, w.Body)
+		}
+		want := []string{"summary of first part", "", "summary of second part"}
+		for i := range want {
+			if summaries[i] != want[i] {
+				t.Errorf("round %d: summaries[%d] = %q, want %q", round, i, summaries[i], want[i])
+			}
+		}
+	}
+	if f.calls != 2 {
+		t.Errorf("LLM calls = %d, want 2 (the second round served from the entry)", f.calls)
+	}
+}
+
+func TestSectionSummariesLimit(t *testing.T) {
+	useConfig(t, func(c *config) { c.SectionSummaries, c.MaxSummarySections = true, 2 })
+	f := &fakeSummarizer{}
+	useFakeSummarizer(t, f)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, ok := sectionSummaries(w, r, "unused", transcriptEntry{}, []string{"a", "", "b", "c"}); ok {
+		t.Fatal("want the request rejected")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if f.calls != 0 {
+		t.Errorf("LLM calls = %d, want none", f.calls)
+	}
+}
+
+func TestExtractiveSummary(t *testing.T) {
+	tests := []struct {
+		name       string
+		utterances []string
+		want       string
+	}{
+		{"empty", nil, ""},
+		{"single", []string{"Only one"}, "Only one"},
+		{"first longest last", []string{"Hi all.", "We ship the new release on Friday.", "Ok.", "Bye."}, "Hi all. We ship the new release on Friday. Bye."},
+		{"longest is first", []string{"This opening remark is the longest.", "Short.", "End."}, "This opening remark is the longest. End."},
+		{"longest is last", []string{"Hi.", "Ok.", "Closing with the longest remark."}, "Hi. Closing with the longest remark."},
+		{"ties keep the earlier", []string{"a", "bbb", "ccc", "d"}, "a bbb d"},
+		{"blank picks skipped", []string{"  ", "The longest line here", "\t"}, "The longest line here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			utterances := make([]CleanUtterance, len(tt.utterances))
+			for i, text := range tt.utterances {
+				utterances[i] = CleanUtterance{Text: text}
+			}
+			got := extractiveSummary(utterances)
+			if got.Summary != tt.want {
+				t.Errorf("summary = %q, want %q", got.Summary, tt.want)
+			}
+			if got.Source != summarySourceFallback || got.ActionItems == nil || len(got.ActionItems) != 0 {
+				t.Errorf("source %q, action items %#v, want %q and none", got.Source, got.ActionItems, summarySourceFallback)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// TopicLabel is one IAB category detected in a stretch of the transcript.
+type TopicLabel struct {
+	Label     string  `json:"label"`
+	Relevance float64 `json:"relevance"`
+}
+
+// Topic is a stretch of the transcript and the categories detected in it.
+// Start and end are in seconds.
+type Topic struct {
+	Text   string       `json:"text"`
+	Start  float64      `json:"start"`
+	End    float64      `json:"end"`
+	Labels []TopicLabel `json:"labels"`
+}
+
+// TopicReport is the topic detection result of a transcription.
+// Summary maps each category to its relevance for the whole recording.
+type TopicReport struct {
+	Status  string             `json:"status"`
+	Topics  []Topic            `json:"topics"`
+	Summary map[string]float64 `json:"summary"`
+}
+
+// topicStatusDisabled is the status of the empty report served when topic detection was not requested.
+const topicStatusDisabled = "disabled"
+
+// toTopicReport converts AssemblyAI's topic detection result,
+// turning the millisecond timestamps into seconds.
+func toTopicReport(result assemblyai.TopicDetectionModelResult) *TopicReport {
+	report := &TopicReport{
+		Status:  string(result.Status),
+		Topics:  make([]Topic, len(result.Results)),
+		Summary: result.Summary,
+	}
+	if report.Summary == nil {
+		report.Summary = map[string]float64{}
+	}
+
+	for i, r := range result.Results {
+		labels := make([]TopicLabel, len(r.Labels))
+		for j, l := range r.Labels {
+			labels[j] = TopicLabel{Label: assemblyai.ToString(l.Label), Relevance: assemblyai.ToFloat64(l.Relevance)}
+		}
+		report.Topics[i] = Topic{
+			Text:   assemblyai.ToString(r.Text),
+			Start:  float64(assemblyai.ToInt64(r.Timestamp.Start)) / 1000.0,
+			End:    float64(assemblyai.ToInt64(r.Timestamp.End)) / 1000.0,
+			Labels: labels,
+		}
+	}
+	return report
+}
+
+// handleGetTopics returns the topics detected in a transcription.
+// Topic detection must have been requested with ?topics=true on upload;
+// otherwise it returns an empty report with status topicStatusDisabled.
+func handleGetTopics(w http.ResponseWriter, r *http.Request) {
+	entry, ok := loadEntry(w, r)
+	if !ok {
+		return
+	}
+	report := entry.Topics
+	if report == nil {
+		report = &TopicReport{Status: topicStatusDisabled, Topics: []Topic{}, Summary: map[string]float64{}}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+func TestToTopicReport(t *testing.T) {
+	var result assemblyai.TopicDetectionModelResult
+	raw := `{
+		"status": "success",
+		"results": [
+			{"text": "Budget for next quarter", "timestamp": {"start": 1500, "end": 4250},
+			 "labels": [{"label": "Business>Finance", "relevance": 0.91}, {"label": "Careers", "relevance": 0.2}]},
+			{"text": "Lunch", "timestamp": {"start": 5000, "end": 5600}}
+		],
+		"summary": {"Business>Finance": 0.8}
+	}`
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	got := toTopicReport(result)
+	want := &TopicReport{
+		Status: "success",
+		Topics: []Topic{
+			{Text: "Budget for next quarter", Start: 1.5, End: 4.25, Labels: []TopicLabel{
+				{Label: "Business>Finance", Relevance: 0.91},
+				{Label: "Careers", Relevance: 0.2},
+			}},
+			{Text: "Lunch", Start: 5, End: 5.6, Labels: []TopicLabel{}},
+		},
+		Summary: map[string]float64{"Business>Finance": 0.8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toTopicReport = %+v, want %+v", got, want)
+	}
+}
+
+func TestToTopicReportEmpty(t *testing.T) {
+	got := toTopicReport(assemblyai.TopicDetectionModelResult{Status: "unavailable"})
+	want := &TopicReport{Status: "unavailable", Topics: []Topic{}, Summary: map[string]float64{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toTopicReport = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"github.com/google/uuid"
+)
+
+// cleanUtterances converts provider utterances to the output format,
+// turning the millisecond timestamps into seconds.
+func cleanUtterances(utterances []Utterance) []CleanUtterance {
+	cleaned := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		words := make([]CleanWord, len(u.Words))
+		for j, w := range u.Words {
+			words[j] = CleanWord{
+				Text:       w.Text,
+				Start:      w.Start / 1000.0,
+				End:        w.End / 1000.0,
+				Confidence: w.Confidence,
+			}
+		}
+		cleaned[i] = CleanUtterance{
+			Text:       u.Text,
+			Speaker:    u.Speaker,
+			Start:      u.Start / 1000.0,
+			End:        u.End / 1000.0,
+			Confidence: u.Confidence,
+			Words:      words,
+		}
+	}
+	return cleaned
+}
+
+// completeTranscription waits for a submitted transcription, post-processes
+// the result, and stores it under connectionID. A failed summary is only logged,
+// so the transcript is still stored. onStatus is passed on to waitUntilCompleted.
+// The outcome is posted to the callback_url of opts, if any, without waiting for delivery.
+// It returns the entry as built, or on failure the WebSocket error code of the
+// failing stage with the error; failures are not stored, which is up to the caller.
+func completeTranscription(ctx context.Context, t Transcriber, transcriptID, connectionID string, params *assemblyai.TranscriptOptionalParams, opts ingestOptions, onStatus func(assemblyai.TranscriptStatus)) (transcriptEntry, string, error) {
+	logger := loggerFrom(ctx).With("transcript_id", transcriptID)
+	if err := waitUntilCompleted(ctx, t, transcriptID, onStatus); err != nil {
+		logger.Error("Polling failed", "error", err)
+		return failTranscription(logger, connectionID, opts, pollErrorCode(err), err)
+	}
+
+	utterances, err := fetchUtterances(ctx, jobPoller.clock, t, transcriptID)
+	if err != nil {
+		logger.Error("Failed to get utterances", "error", err)
+		return failTranscription(logger, connectionID, opts, errCodeFetch, err)
+	}
+
+	insights := transcriptInsights{Language: string(params.LanguageCode)}
+	if assemblyai.ToBool(params.IABCategories) || assemblyai.ToBool(params.AutoChapters) ||
+		assemblyai.ToBool(params.LanguageDetection) || assemblyai.ToBool(params.Summarization) ||
+		assemblyai.ToBool(params.SentimentAnalysis) {
+		insights, err = t.Insights(ctx, transcriptID)
+		if err != nil {
+			logger.Error("Failed to get transcript insights", "error", err)
+			return failTranscription(logger, connectionID, opts, errCodeFetch, err)
+		}
+	}
+	if insights.Language == "" {
+		insights.Language = defaultLanguage
+	}
+
+	var summary *MeetingSummary
+	if opts.Summarize {
+		summary, err = t.Summarize(ctx, transcriptID)
+		if err != nil {
+			logger.Warn("Summarization failed, storing transcript without summary", "error", err)
+		}
+	}
+	if assemblyai.ToBool(params.Summarization) {
+		if summary == nil {
+			summary = &MeetingSummary{ActionItems: []string{}}
+		}
+		summary.Summary = insights.Summary
+		summary.Type = string(params.SummaryType)
+		summary.Model = string(params.SummaryModel)
+	}
+
+	var speakerAttrs map[string]SpeakerDemographics
+	if sa, ok := t.(SpeakerAttributes); ok && cfg.SpeakerAttributes {
+		speakerAttrs, err = sa.SpeakerAttributes(ctx, transcriptID)
+		if err != nil {
+			logger.Warn("Failed to get speaker attributes, storing transcript without them", "error", err)
+		}
+	}
+
+	cleaned := cleanUtterances(utterances)
+
+	if cfg.MaxUtteranceSeconds > 0 {
+		cleaned = splitLongUtterances(cleaned, cfg.MaxUtteranceSeconds)
+	}
+
+	if cfg.NormalizeNumbers {
+		normalizeUtterances(cleaned)
+	}
+
+	markLowConfidence(cleaned, cfg.LowConfidenceThreshold)
+
+	if cfg.Blocklist != nil {
+		maskUtterances(cleaned, cfg.Blocklist)
+	}
+
+	if insights.Sentiments != nil {
+		alignSentiments(cleaned, insights.Sentiments)
+	}
+
+	if opts.RoleHeuristic != "" {
+		roles := assignRoles(cleaned, opts.RoleHeuristic, cfg.SpeakerRoles)
+		// The labels come from cleaned itself, so none can be unknown.
+		cleaned, _ = renameSpeakers(cleaned, roles)
+		speakerAttrs = renameSpeakerAttributes(speakerAttrs, roles)
+	}
+
+	var translations map[string][]string
+	if tr, ok := t.(Translator); ok && opts.TranslateTo != "" {
+		tctx, cancel := context.WithTimeout(ctx, cfg.TranslationTimeout)
+		texts, err := translateUtterances(tctx, tr, cleaned, opts.TranslateTo)
+		cancel()
+		if err != nil {
+			logger.Warn("Translation failed, storing what was translated", "lang", opts.TranslateTo,
+				"translated", len(texts), "utterances", len(cleaned), "error", err)
+		}
+		if len(texts) > 0 {
+			translations = map[string][]string{opts.TranslateTo: texts}
+		}
+	}
+
+	entry := transcriptEntry{
+		Utterances: cleaned,
+		CostCenter: opts.CostCenter,
+		CreatedAt:  opts.CreatedAt,
+		Provider:   t.Name(),
+		Language:   insights.Language,
+		Topics:     insights.Topics,
+		Chapters:   insights.Chapters,
+		Summary:    summary,
+
+		SpeakerAttributes: speakerAttrs,
+		Translations:      translations,
+	}
+	if cfg.RejectNoSpeech && !hasSpeech(cleaned) {
+		logger.Info("No speech detected")
+		return failTranscription(logger, connectionID, opts, errCodeNoSpeech, ErrNoSpeech)
+	}
+
+	saveTranscription(connectionID, entry)
+	logger.Info("Transcription stored", "utterances", len(cleaned), "provider", entry.Provider)
+	if opts.CallbackURL != "" {
+		notifyWebhook(logger, opts.CallbackURL, webhookPayload{ConnectionID: connectionID, Status: webhookCompleted, Language: entry.Language, CostCenter: entry.CostCenter, Utterances: cleaned})
+	}
+	return entry, "", nil
+}
+
+// failTranscription notifies the callback_url of opts, if any, that the transcription
+// under connectionID failed with err, and returns code and err as completeTranscription does.
+func failTranscription(logger *slog.Logger, connectionID string, opts ingestOptions, code string, err error) (transcriptEntry, string, error) {
+	if opts.CallbackURL != "" {
+		notifyWebhook(logger, opts.CallbackURL, webhookPayload{ConnectionID: connectionID, Status: webhookFailed, CostCenter: opts.CostCenter, Error: err.Error()})
+	}
+	return transcriptEntry{}, code, err
+}
+
+// parseAudioURL checks that raw is an absolute http or https URL.
+func parseAudioURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// handleTranscribeURL starts a transcription of hosted audio.
+// It expects a JSON body of the form {"audio_url": "https://..."}, optionally with
+// a "callback_url", and accepts the same query parameters as /ws. It responds with 202 and {"connection_id": "..."}
+// right away; the result is stored under that ID once done, as for uploads,
+// and a failure is stored so the GET endpoint can report it.
+func handleTranscribeURL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		AudioURL    string `json:"audio_url"`
+		CallbackURL string `json:"callback_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	audioURL, ok := parseAudioURL(body.AudioURL)
+	if !ok {
+		http.Error(w, "audio_url must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	params, err := transcriptParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseIngestOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.CallbackURL != "" {
+		if opts.CallbackURL, err = parseCallbackURL(body.CallbackURL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !allowSubmission(r) {
+		loggerFrom(r.Context()).Warn("Submission rate limited", "client", clientIP(r))
+		http.Error(w, "Too many transcriptions, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
+	if apiKey == "" {
+		loggerFrom(r.Context()).Error("API key not found in environment")
+		http.Error(w, "Transcription provider is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	transcriber := newAssemblyAITranscriber(apiKey, "")
+
+	release, ok := transcriptionWorkers.tryAcquire()
+	if !ok {
+		loggerFrom(r.Context()).Warn("Rejected transcription, all transcription workers busy")
+		http.Error(w, "Server busy, try again later", http.StatusServiceUnavailable)
+		return
+	}
+
+	connectionID := uuid.New().String()
+	logger := loggerFrom(r.Context()).With("conn_id", connectionID)
+	logger.Info("New URL transcription", "cost_center", opts.CostCenter)
+
+	ctx, cancel := context.WithTimeout(withRetryBudget(withLogger(context.Background(), logger), cfg.RequestRetryBudget), cfg.TranscriptionTimeout)
+	inflight.Add(connectionID, statusProcessing, cancel)
+
+	clock := jobPoller.clock
+	finishMetrics := startTranscriptionMetrics(clock)
+	transcriptID, err := transcriber.SubmitURL(ctx, audioURL, params)
+	if err != nil {
+		finishMetrics(time.Time{}, err)
+		release()
+		cancel()
+		inflight.Remove(connectionID)
+		logger.Error("Transcription submit failed", "error", err)
+		http.Error(w, "Transcription failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	submitted := clock.Now()
+
+	backgroundJobs.Go(func() {
+		defer release()
+		defer cancel()
+		defer inflight.Remove(connectionID)
+		_, _, err := completeTranscription(ctx, transcriber, transcriptID, connectionID, params, opts, nil)
+		finishMetrics(submitted, err)
+		if err != nil {
+			saveFailure(connectionID, err.Error())
+		}
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"connection_id": connectionID})
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// useTestPoller replaces jobPoller with one that polls every millisecond
+// for the rest of the test.
+func useTestPoller(t *testing.T) {
+	t.Helper()
+	prev := jobPoller
+	ctx, cancel := context.WithCancel(context.Background())
+	jobPoller = newPoller(time.Millisecond, 1, realClock{})
+	go jobPoller.run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		jobPoller = prev
+	})
+}
+
+func TestCompleteTranscriptionRejectsNoSpeech(t *testing.T) {
+	useTestPoller(t)
+
+	silent := []Utterance{{Speaker: "A", Text: "  "}, {Speaker: "B", Text: "\n\t"}}
+	spoken := []Utterance{{Speaker: "A", Text: " "}, {Speaker: "B", Text: "hello", Start: 1000, End: 2000}}
+	tests := []struct {
+		name       string
+		id         string
+		reject     bool
+		utterances []Utterance
+		wantCode   string
+		wantStatus int
+	}{
+		{"whitespace rejected", "silent-rejected", true, silent, errCodeNoSpeech, http.StatusNotFound},
+		{"whitespace stored when disabled", "silent-stored", false, silent, "", http.StatusOK},
+		{"speech stored", "spoken", true, spoken, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, func(c *config) { c.RejectNoSpeech = tt.reject })
+			id := tt.id
+			t.Cleanup(func() { deleteTranscription(id) })
+			ft := &fakeTranscriber{utterances: [][]Utterance{tt.utterances}}
+
+			entry, code, err := completeTranscription(context.Background(), ft, "transcript-1", id, &assemblyai.TranscriptOptionalParams{}, ingestOptions{}, nil)
+			if code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+			if tt.wantCode != "" {
+				if !errors.Is(err, ErrNoSpeech) {
+					t.Errorf("err = %v, want %v", err, ErrNoSpeech)
+				}
+			} else if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			} else if len(entry.Utterances) != len(tt.utterances) {
+				t.Errorf("stored %d utterances, want %d", len(entry.Utterances), len(tt.utterances))
+			}
+
+			// A rejected transcript is left for the caller to store as failed.
+			w := serveTranscription(handleGetTranscription, id, "format=json")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestCompleteTranscriptionReportsProviderError(t *testing.T) {
+	useTestPoller(t)
+	const id = "provider-error"
+	t.Cleanup(func() { deleteTranscription(id) })
+	ft := &fakeTranscriber{statuses: []jobStatus{
+		{Status: assemblyai.TranscriptStatusQueued},
+		{Status: assemblyai.TranscriptStatusError, Error: "unsupported codec"},
+	}}
+
+	var seen []assemblyai.TranscriptStatus
+	onStatus := func(st assemblyai.TranscriptStatus) { seen = append(seen, st) }
+	_, code, err := completeTranscription(context.Background(), ft, "transcript-1", id, &assemblyai.TranscriptOptionalParams{}, ingestOptions{}, onStatus)
+
+	if !errors.Is(err, ErrTranscriptFailed) {
+		t.Fatalf("err = %v, want %v", err, ErrTranscriptFailed)
+	}
+	if code != pollErrorCode(err) {
+		t.Errorf("code = %q, want %q", code, pollErrorCode(err))
+	}
+	if want := []assemblyai.TranscriptStatus{assemblyai.TranscriptStatusQueued, assemblyai.TranscriptStatusError}; len(seen) != 2 || seen[0] != want[0] || seen[1] != want[1] {
+		t.Errorf("statuses = %v, want %v", seen, want)
+	}
+	if ft.utteranceCalls() != 0 {
+		t.Errorf("fetched utterances %d times, want 0", ft.utteranceCalls())
+	}
+}
+
+func TestCostCenterRoundTrip(t *testing.T) {
+	useTestPoller(t)
+	useConfig(t, func(c *config) { c.WebhookAttempts = 1 })
+
+	payloads := make(chan webhookPayload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhookPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		payloads <- p
+	}))
+	t.Cleanup(receiver.Close)
+
+	opts, err := parseIngestOptions(url.Values{"cost_center": {"sales-emea"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.CallbackURL = receiver.URL
+	const id = "cost-center"
+	t.Cleanup(func() { deleteTranscription(id) })
+	ft := &fakeTranscriber{utterances: [][]Utterance{{{Speaker: "A", Text: "hello", End: 1000}}}}
+
+	if _, _, err := completeTranscription(context.Background(), ft, "transcript-1", id, &assemblyai.TranscriptOptionalParams{}, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveTranscription(handleGetTranscription, id, "format=json")
+	if got := w.Header().Get("X-Cost-Center"); got != "sales-emea" {
+		t.Errorf("X-Cost-Center = %q, want sales-emea", got)
+	}
+	listed := false
+	for _, st := range listTranscriptions(0, maxListLimit) {
+		if st.ID == id {
+			listed = true
+			if st.CostCenter != "sales-emea" {
+				t.Errorf("listed cost_center = %q, want sales-emea", st.CostCenter)
+			}
+		}
+	}
+	if !listed {
+		t.Errorf("%s not listed", id)
+	}
+	select {
+	case p := <-payloads:
+		if p.CostCenter != "sales-emea" {
+			t.Errorf("webhook cost_center = %q, want sales-emea", p.CostCenter)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivered")
+	}
+}
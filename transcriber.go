@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// jobStatus is the lightweight status of a transcription job.
+// Error is only set when Status is TranscriptStatusError.
+type jobStatus struct {
+	Status assemblyai.TranscriptStatus
+	Error  string
+}
+
+// Transcriber submits audio to a transcription provider and retrieves the results.
+// Polling only goes through Status, so providers with a cheap status call
+// transfer the full transcript just once, through Utterances.
+type Transcriber interface {
+	// Name identifies the provider and model, and is stored with each result.
+	Name() string
+	// Submit uploads the audio and starts a transcription, returning its ID.
+	// It does not wait for the transcription to finish.
+	Submit(ctx context.Context, audio io.Reader, params *assemblyai.TranscriptOptionalParams) (string, error)
+	// SubmitURL starts a transcription of audio hosted at audioURL, returning its ID.
+	SubmitURL(ctx context.Context, audioURL string, params *assemblyai.TranscriptOptionalParams) (string, error)
+	// Status returns the current status of a transcription.
+	// Implementations should use the cheapest call the provider offers.
+	Status(ctx context.Context, transcriptID string) (jobStatus, error)
+	// Utterances fetches the utterances of a completed transcription.
+	Utterances(ctx context.Context, transcriptID string) ([]Utterance, error)
+	// Insights fetches the language, topics, chapters, summary, and sentiments of a completed transcription.
+	// Topics, chapters, the summary, and sentiments are only set when they were enabled on submission.
+	Insights(ctx context.Context, transcriptID string) (transcriptInsights, error)
+	// Summarize generates a summary and action items for a completed transcription.
+	Summarize(ctx context.Context, transcriptID string) (*MeetingSummary, error)
+}
+
+// transcriptInsights holds the optional analyses of a transcription.
+// A nil field means the analysis was not requested.
+// Language is the language the provider transcribed in, which may have been detected.
+type transcriptInsights struct {
+	Topics   *TopicReport
+	Chapters []Chapter
+	Language string
+	// Summary is the AssemblyAI summary, empty unless summarization was enabled.
+	Summary string
+	// Sentiments are the per-sentence sentiments, nil unless sentiment analysis was enabled.
+	Sentiments []sentimentSpan
+}
+
+// ErrProviderUnavailable is returned when the provider answers with something
+// that is not JSON at all, such as an HTML error page from a gateway or a
+// truncated body. It usually means a transient outage, so callers may retry.
+var ErrProviderUnavailable = errors.New("transcription provider unavailable")
+
+// maxLoggedBody bounds how much of a non-JSON provider response is logged.
+const maxLoggedBody = 512
+
+// jsonTransport checks that successful provider responses are JSON.
+// The SDK decodes a body only when its Content-Type is application/json and
+// otherwise reports success with an empty result, so a gateway's HTML page
+// would be taken for a completed transcript. Such bodies, and ones that are not
+// valid JSON, yield an error wrapping ErrProviderUnavailable instead. Valid JSON
+// that doesn't match the schema is left to the SDK, which fails with a plain decode error.
+type jsonTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends the request and checks the body of a 2xx response.
+func (t *jsonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 || resp.StatusCode == http.StatusNoContent {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading response with status %d: %w", ErrProviderUnavailable, resp.StatusCode, err)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/json" || !json.Valid(body) {
+		loggerFrom(req.Context()).Warn("Provider returned a non-JSON response", "path", req.URL.Path,
+			"http_status", resp.StatusCode, "content_type", resp.Header.Get("Content-Type"), "body", string(body[:min(len(body), maxLoggedBody)]))
+		return nil, fmt.Errorf("%w: non-JSON response with status %d", ErrProviderUnavailable, resp.StatusCode)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// assemblyAITranscriber is the Transcriber backed by the AssemblyAI API.
+// AssemblyAI's status call returns the full transcript, so the transcript seen
+// as completed by Status is kept and reused by Utterances and Insights
+// instead of fetching it again.
+type assemblyAITranscriber struct {
+	client *assemblyai.Client
+	// speechModel is the model used unless the request names one; empty means the provider default.
+	speechModel assemblyai.SpeechModel
+
+	mu             sync.Mutex
+	completed      map[string]assemblyai.Transcript
+	utterancesRead map[string]bool
+}
+
+// newAssemblyAITranscriber creates a Transcriber for the given AssemblyAI API key.
+// speechModel, such as "nano" or "best", may be empty for the provider default.
+func newAssemblyAITranscriber(apiKey, speechModel string) *assemblyAITranscriber {
+	return &assemblyAITranscriber{
+		client: assemblyai.NewClientWithOptions(
+			assemblyai.WithAPIKey(apiKey),
+			assemblyai.WithHTTPClient(providerHTTPClient),
+		),
+		speechModel:    assemblyai.SpeechModel(speechModel),
+		completed:      make(map[string]assemblyai.Transcript),
+		utterancesRead: make(map[string]bool),
+	}
+}
+
+// transcript returns the completed transcript kept by Status, or fetches it.
+// With refresh set, it always fetches, replacing the kept transcript.
+func (t *assemblyAITranscriber) transcript(ctx context.Context, transcriptID string, refresh bool) (assemblyai.Transcript, error) {
+	t.mu.Lock()
+	tr, ok := t.completed[transcriptID]
+	t.mu.Unlock()
+	if ok && !refresh {
+		return tr, nil
+	}
+
+	tr, err := t.client.Transcripts.Get(ctx, transcriptID)
+	if err != nil {
+		return tr, err
+	}
+	t.mu.Lock()
+	t.completed[transcriptID] = tr
+	t.mu.Unlock()
+	return tr, nil
+}
+
+// Name returns "assemblyai", followed by the speech model if one is set.
+func (t *assemblyAITranscriber) Name() string {
+	if t.speechModel == "" {
+		return "assemblyai"
+	}
+	return "assemblyai/" + string(t.speechModel)
+}
+
+// withModel returns params with the transcriber's speech model applied,
+// unless params already name one. The caller's params are not modified.
+func (t *assemblyAITranscriber) withModel(params *assemblyai.TranscriptOptionalParams) *assemblyai.TranscriptOptionalParams {
+	if t.speechModel == "" || params.SpeechModel != "" {
+		return params
+	}
+	p := *params
+	p.SpeechModel = t.speechModel
+	return &p
+}
+
+// Submit uploads the audio to AssemblyAI and submits it for transcription.
+func (t *assemblyAITranscriber) Submit(ctx context.Context, audio io.Reader, params *assemblyai.TranscriptOptionalParams) (string, error) {
+	transcript, err := t.client.Transcripts.SubmitFromReader(ctx, audio, t.withModel(params))
+	if err != nil {
+		return "", err
+	}
+	return *transcript.ID, nil
+}
+
+// SubmitURL submits hosted audio to AssemblyAI for transcription.
+func (t *assemblyAITranscriber) SubmitURL(ctx context.Context, audioURL string, params *assemblyai.TranscriptOptionalParams) (string, error) {
+	transcript, err := t.client.Transcripts.SubmitFromURL(ctx, audioURL, t.withModel(params))
+	if err != nil {
+		return "", err
+	}
+	return *transcript.ID, nil
+}
+
+// Status returns the status of the transcript.
+// AssemblyAI has no status-only endpoint, so this is a regular transcript GET.
+func (t *assemblyAITranscriber) Status(ctx context.Context, transcriptID string) (jobStatus, error) {
+	tr, err := t.client.Transcripts.Get(ctx, transcriptID)
+	if err != nil {
+		return jobStatus{}, err
+	}
+	if tr.Status == assemblyai.TranscriptStatusCompleted {
+		t.mu.Lock()
+		t.completed[transcriptID] = tr
+		t.mu.Unlock()
+	}
+	return jobStatus{Status: tr.Status, Error: assemblyai.ToString(tr.Error)}, nil
+}
+
+// Utterances returns the utterances of a completed transcript.
+// The first call uses the transcript kept by Status; later calls fetch it
+// again, so a retry sees the provider's latest result.
+func (t *assemblyAITranscriber) Utterances(ctx context.Context, transcriptID string) ([]Utterance, error) {
+	t.mu.Lock()
+	refresh := t.utterancesRead[transcriptID]
+	t.utterancesRead[transcriptID] = true
+	t.mu.Unlock()
+
+	tr, err := t.transcript(ctx, transcriptID, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return toUtterances(tr.Utterances), nil
+}
+
+// toUtterances maps the SDK's utterances to Utterance, keeping milliseconds.
+func toUtterances(utterances []assemblyai.TranscriptUtterance) []Utterance {
+	out := make([]Utterance, len(utterances))
+	for i, u := range utterances {
+		words := make([]Word, len(u.Words))
+		for j, w := range u.Words {
+			words[j] = Word{
+				Text:       assemblyai.ToString(w.Text),
+				Start:      float64(assemblyai.ToInt64(w.Start)),
+				End:        float64(assemblyai.ToInt64(w.End)),
+				Confidence: assemblyai.ToFloat64(w.Confidence),
+			}
+		}
+		out[i] = Utterance{
+			Text:       assemblyai.ToString(u.Text),
+			Speaker:    assemblyai.ToString(u.Speaker),
+			Start:      float64(assemblyai.ToInt64(u.Start)),
+			End:        float64(assemblyai.ToInt64(u.End)),
+			Confidence: assemblyai.ToFloat64(u.Confidence),
+			Words:      words,
+		}
+	}
+	return out
+}
+
+// Insights returns the language, topic detection, chapter, summarization, and sentiment results of a completed transcript.
+// The transcript echoes which models were enabled, so results not requested stay nil.
+func (t *assemblyAITranscriber) Insights(ctx context.Context, transcriptID string) (transcriptInsights, error) {
+	tr, err := t.transcript(ctx, transcriptID, false)
+	if err != nil {
+		return transcriptInsights{}, err
+	}
+
+	insights := transcriptInsights{Language: string(tr.LanguageCode)}
+	if assemblyai.ToBool(tr.IABCategories) {
+		insights.Topics = toTopicReport(tr.IABCategoriesResult)
+	}
+	if assemblyai.ToBool(tr.AutoChapters) {
+		insights.Chapters = toChapters(tr.Chapters)
+	}
+	if assemblyai.ToBool(tr.Summarization) {
+		insights.Summary = strings.TrimSpace(assemblyai.ToString(tr.Summary))
+	}
+	if assemblyai.ToBool(tr.SentimentAnalysis) {
+		insights.Sentiments = toSentimentSpans(tr.SentimentAnalysisResults)
+	}
+	return insights, nil
+}
+
+// SummarizeSection asks LeMUR for a one-sentence summary of text.
+func (t *assemblyAITranscriber) SummarizeSection(ctx context.Context, text string) (string, error) {
+	resp, err := t.client.LeMUR.Task(ctx, assemblyai.LeMURTaskParams{
+		Prompt:          assemblyai.String("Summarize this part of a meeting in one short sentence. Answer with the sentence only."),
+		LeMURBaseParams: assemblyai.LeMURBaseParams{InputText: assemblyai.String(text)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return assemblyai.ToString(resp.Response), nil
+}
+
+// Summarize asks LeMUR for a concise summary and the action items of a completed transcript.
+func (t *assemblyAITranscriber) Summarize(ctx context.Context, transcriptID string) (*MeetingSummary, error) {
+	base := assemblyai.LeMURBaseParams{TranscriptIDs: []string{transcriptID}}
+
+	summary, err := t.client.LeMUR.Summarize(ctx, assemblyai.LeMURSummaryParams{
+		LeMURBaseParams: base,
+		AnswerFormat:    assemblyai.String("A short paragraph"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.client.LeMUR.ActionItems(ctx, assemblyai.LeMURActionItemsParams{
+		LeMURBaseParams: base,
+		AnswerFormat:    assemblyai.String("Bullet Points"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MeetingSummary{
+		Summary:     strings.TrimSpace(assemblyai.ToString(summary.Response)),
+		ActionItems: parseBulletList(assemblyai.ToString(items.Response)),
+	}, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+)
+
+// fakeTranscriber is a Transcriber that answers from canned results.
+// Status returns statuses in order, repeating the last one; Utterances does the same.
+type fakeTranscriber struct {
+	name       string
+	submitErr  error
+	statuses   []jobStatus
+	utterances [][]Utterance
+	insights   transcriptInsights
+
+	mu       sync.Mutex
+	submits  int
+	polls    int
+	fetches  int
+	lastSent []byte
+}
+
+func (f *fakeTranscriber) Name() string {
+	if f.name == "" {
+		return "fake"
+	}
+	return f.name
+}
+
+func (f *fakeTranscriber) Submit(ctx context.Context, audio io.Reader, params *assemblyai.TranscriptOptionalParams) (string, error) {
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return "", err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.submits++
+	f.lastSent = data
+	if f.submitErr != nil {
+		return "", f.submitErr
+	}
+	return "transcript-1", nil
+}
+
+func (f *fakeTranscriber) SubmitURL(ctx context.Context, audioURL string, params *assemblyai.TranscriptOptionalParams) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.submits++
+	if f.submitErr != nil {
+		return "", f.submitErr
+	}
+	return "transcript-1", nil
+}
+
+func (f *fakeTranscriber) Status(ctx context.Context, transcriptID string) (jobStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.polls++
+	if len(f.statuses) == 0 {
+		return jobStatus{Status: assemblyai.TranscriptStatusCompleted}, nil
+	}
+	return f.statuses[min(f.polls, len(f.statuses))-1], nil
+}
+
+func (f *fakeTranscriber) Utterances(ctx context.Context, transcriptID string) ([]Utterance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetches++
+	if len(f.utterances) == 0 {
+		return nil, nil
+	}
+	return f.utterances[min(f.fetches, len(f.utterances))-1], nil
+}
+
+func (f *fakeTranscriber) Insights(ctx context.Context, transcriptID string) (transcriptInsights, error) {
+	return f.insights, nil
+}
+
+func (f *fakeTranscriber) Summarize(ctx context.Context, transcriptID string) (*MeetingSummary, error) {
+	return nil, nil
+}
+
+func (f *fakeTranscriber) utteranceCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fetches
+}
+
+// newTestAssemblyAI returns an assemblyAITranscriber whose API calls go to handler,
+// through the same jsonTransport as the provider client but without retries.
+func newTestAssemblyAI(t *testing.T, handler http.HandlerFunc) *assemblyAITranscriber {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &assemblyAITranscriber{
+		client: assemblyai.NewClientWithOptions(
+			assemblyai.WithBaseURL(srv.URL),
+			assemblyai.WithHTTPClient(&http.Client{Transport: &jsonTransport{base: http.DefaultTransport}}),
+		),
+		completed:      make(map[string]assemblyai.Transcript),
+		utterancesRead: make(map[string]bool),
+	}
+}
+
+func TestAssemblyAIStatusRejectsNonJSONResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		unavailable bool
+	}{
+		{"html error page", "text/html", "<html><body>502 Bad Gateway</body></html>", true},
+		{"html labelled as json", "application/json", "<html><body>Service Unavailable</body></html>", true},
+		{"truncated json", "application/json", `{"id": "t1", "status": "compl`, true},
+		{"missing content type", "", `{"id": "t1", "status": "completed"}`, true},
+		{"json not matching the schema", "application/json", `{"id": "t1", "status": 5}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newTestAssemblyAI(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header()["Content-Type"] = []string{tt.contentType}
+				io.WriteString(w, tt.body)
+			})
+
+			st, err := at.Status(context.Background(), "t1")
+			if err == nil {
+				t.Fatalf("Status = %+v, want an error", st)
+			}
+			if errors.Is(err, ErrProviderUnavailable) != tt.unavailable {
+				t.Errorf("err = %v, want ErrProviderUnavailable: %v", err, tt.unavailable)
+			}
+		})
+	}
+}
+
+func TestAssemblyAIStatusAcceptsJSONResponse(t *testing.T) {
+	at := newTestAssemblyAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, `{"id": "t1", "status": "processing"}`)
+	})
+
+	st, err := at.Status(context.Background(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != assemblyai.TranscriptStatusProcessing {
+		t.Errorf("status = %q, want %q", st.Status, assemblyai.TranscriptStatusProcessing)
+	}
+}
+
+func TestCompleteTranscriptionReportsProviderUnavailable(t *testing.T) {
+	useTestPoller(t)
+	const id = "provider-unavailable"
+	t.Cleanup(func() { deleteTranscription(id) })
+	at := newTestAssemblyAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body>We'll be right back</body></html>")
+	})
+
+	_, code, err := completeTranscription(context.Background(), at, "t1", id, &assemblyai.TranscriptOptionalParams{}, ingestOptions{}, nil)
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("err = %v, want %v", err, ErrProviderUnavailable)
+	}
+	if code != errCodeProviderUnavailable || !retryableErrCodes[code] {
+		t.Errorf("code = %q (retryable %v), want retryable %q", code, retryableErrCodes[code], errCodeProviderUnavailable)
+	}
+	if _, ok, _ := getTranscription(id); ok {
+		t.Error("transcript stored after a non-JSON provider response")
+	}
+}
+
+func TestAssemblyAIFetchesTranscriptOncePerPoll(t *testing.T) {
+	statuses := []string{"queued", "processing", "completed"}
+	var mu sync.Mutex
+	gets := 0
+	at := newTestAssemblyAI(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != "GET" || r.URL.Path != "/v2/transcript/t1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		status := statuses[min(gets, len(statuses)-1)]
+		gets++
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id": "t1", "status": "`+status+`", "language_code": "en_us",
+			"utterances": [{"speaker": "A", "text": "hello", "start": 0, "end": 1000, "confidence": 0.9, "words": []}]}`)
+	})
+
+	for _, want := range statuses {
+		st, err := at.Status(context.Background(), "t1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(st.Status) != want {
+			t.Fatalf("status = %q, want %q", st.Status, want)
+		}
+	}
+	utterances, err := at.Utterances(context.Background(), "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := at.Insights(context.Background(), "t1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(utterances) != 1 || utterances[0].Text != "hello" {
+		t.Errorf("utterances = %+v, want the completed transcript's", utterances)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gets != len(statuses) {
+		t.Errorf("transcript GETs = %d, want %d, one per poll and none after completion", gets, len(statuses))
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// roundTo rounds a value to the given number of decimal places.
+// Halves are rounded away from zero.
+func roundTo(v float64, precision int) float64 {
+	p := math.Pow(10, float64(precision))
+	return math.Round(v*p) / p
+}
+
+// withPrecision returns a copy of utterances with start and end times rounded
+// to the given number of decimal places. Word timings are rounded as well.
+// The input slice is not modified.
+func withPrecision(utterances []CleanUtterance, precision int) []CleanUtterance {
+	out := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		u.Start = roundTo(u.Start, precision)
+		u.End = roundTo(u.End, precision)
+		if u.Words != nil {
+			words := make([]CleanWord, len(u.Words))
+			for j, w := range u.Words {
+				w.Start = roundTo(w.Start, precision)
+				w.End = roundTo(w.End, precision)
+				words[j] = w
+			}
+			u.Words = words
+		}
+		out[i] = u
+	}
+	return out
+}
+
+// withoutWords returns a copy of utterances with the word timings dropped.
+// The input slice is not modified.
+func withoutWords(utterances []CleanUtterance) []CleanUtterance {
+	out := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		u.Words = nil
+		out[i] = u
+	}
+	return out
+}
+
+// rebase returns a copy of utterances with every timing shifted so the
+// earliest utterance starts at zero. Word timings are shifted as well.
+// The input slice is not modified.
+func rebase(utterances []CleanUtterance) []CleanUtterance {
+	if len(utterances) == 0 {
+		return utterances
+	}
+	offset := utterances[0].Start
+	for _, u := range utterances[1:] {
+		offset = math.Min(offset, u.Start)
+	}
+
+	out := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		u.Start -= offset
+		u.End -= offset
+		if u.Words != nil {
+			words := make([]CleanWord, len(u.Words))
+			for j, w := range u.Words {
+				w.Start -= offset
+				w.End -= offset
+				words[j] = w
+			}
+			u.Words = words
+		}
+		out[i] = u
+	}
+	return out
+}
+
+// utteranceFields maps each selectable output field to its value getter.
+var utteranceFields = map[string]func(CleanUtterance) any{
+	"text":            func(u CleanUtterance) any { return u.Text },
+	"original_text":   func(u CleanUtterance) any { return u.OriginalText },
+	"speaker":         func(u CleanUtterance) any { return u.Speaker },
+	"start":           func(u CleanUtterance) any { return u.Start },
+	"end":             func(u CleanUtterance) any { return u.End },
+	"confidence":      func(u CleanUtterance) any { return u.Confidence },
+	"low_confidence":  func(u CleanUtterance) any { return u.LowConfidence },
+	"words":           func(u CleanUtterance) any { return u.Words },
+	"sentiment":       func(u CleanUtterance) any { return u.Sentiment },
+	"start_ms":        func(u CleanUtterance) any { return toMilliseconds(u.Start) },
+	"end_ms":          func(u CleanUtterance) any { return toMilliseconds(u.End) },
+	"reading_time_ms": func(u CleanUtterance) any { return readingTimeMs(u.Text, cfg.ReadingWPM) },
+}
+
+// toMilliseconds converts a time in seconds to whole milliseconds.
+func toMilliseconds(seconds float64) int64 {
+	return int64(math.Round(seconds * 1000))
+}
+
+// readingTimeMs estimates how long text takes to read at wpm words per minute, in milliseconds.
+// It returns 0 for text without words or a non-positive wpm.
+func readingTimeMs(text string, wpm float64) int64 {
+	if wpm <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(len(tokenize(text))) / wpm * 60000))
+}
+
+// extendedUtterance is an utterance with the optional computed fields of the GET endpoint.
+// Fields left nil are omitted.
+type extendedUtterance struct {
+	CleanUtterance
+	StartMs       *int64 `json:"start_ms,omitempty"`
+	EndMs         *int64 `json:"end_ms,omitempty"`
+	ReadingTimeMs *int64 `json:"reading_time_ms,omitempty"`
+}
+
+// extendUtterances adds integer millisecond timings when milliseconds is set,
+// and the reading time estimate at READING_WPM when readingTime is set.
+func extendUtterances(utterances []CleanUtterance, milliseconds, readingTime bool) []extendedUtterance {
+	out := make([]extendedUtterance, len(utterances))
+	for i, u := range utterances {
+		out[i] = extendedUtterance{CleanUtterance: u}
+		if milliseconds {
+			start, end := toMilliseconds(u.Start), toMilliseconds(u.End)
+			out[i].StartMs, out[i].EndMs = &start, &end
+		}
+		if readingTime {
+			rt := readingTimeMs(u.Text, cfg.ReadingWPM)
+			out[i].ReadingTimeMs = &rt
+		}
+	}
+	return out
+}
+
+// parseFields parses a comma-separated field list such as "text,start".
+// It returns an error naming the first field that is not a known utterance field.
+func parseFields(raw string) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, ok := utteranceFields[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return fields, nil
+}
+
+// projectFields returns each utterance as a map holding only the given fields.
+// The fields must have been validated with parseFields.
+func projectFields(utterances []CleanUtterance, fields []string) []map[string]any {
+	out := make([]map[string]any, len(utterances))
+	for i, u := range utterances {
+		m := make(map[string]any, len(fields))
+		for _, f := range fields {
+			m[f] = utteranceFields[f](u)
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// sortByStart returns a copy of utterances ordered by start time,
+// newest first when desc is true. The input slice is not modified.
+func sortByStart(utterances []CleanUtterance, desc bool) []CleanUtterance {
+	out := make([]CleanUtterance, len(utterances))
+	copy(out, utterances)
+	sort.SliceStable(out, func(i, j int) bool {
+		if desc {
+			return out[i].Start > out[j].Start
+		}
+		return out[i].Start < out[j].Start
+	})
+	return out
+}
+
+// parseKeywords parses a comma-separated keyword list, lowercasing each keyword.
+// It returns an error if the list holds no non-empty keyword.
+func parseKeywords(raw string) ([]string, error) {
+	var kws []string
+	for _, k := range strings.Split(raw, ",") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			kws = append(kws, k)
+		}
+	}
+	if len(kws) == 0 {
+		return nil, fmt.Errorf("no keywords given")
+	}
+	return kws, nil
+}
+
+// filterByKeywords returns the utterances whose text contains any of the keywords.
+// Matching is a case-insensitive substring match; keywords must be lowercase.
+func filterByKeywords(utterances []CleanUtterance, kws []string) []CleanUtterance {
+	out := []CleanUtterance{}
+	for _, u := range utterances {
+		text := strings.ToLower(u.Text)
+		for _, k := range kws {
+			if strings.Contains(text, k) {
+				out = append(out, u)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// speakerOrder returns the distinct speaker labels in order of first appearance.
+// Utterances without a speaker label are skipped.
+func speakerOrder(utterances []CleanUtterance) []string {
+	seen := make(map[string]bool)
+	var order []string
+	for _, u := range utterances {
+		if u.Speaker != "" && !seen[u.Speaker] {
+			seen[u.Speaker] = true
+			order = append(order, u.Speaker)
+		}
+	}
+	return order
+}
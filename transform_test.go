@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"text,start", []string{"text", "start"}, false},
+		{" speaker , sentiment ,", []string{"speaker", "sentiment"}, false},
+		{"text,volume", nil, true},
+		{"", nil, true},
+		{" , ,", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFields(tt.raw)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFields(%q) = %v, %v, want %v, err %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	positive := &Sentiment{Label: "POSITIVE", Confidence: 0.9}
+	utterances := []CleanUtterance{
+		{Text: "hi", Speaker: "A", Start: 1, End: 2, Sentiment: positive},
+		{Text: "bye", Speaker: "B", Start: 2.5, End: 3},
+	}
+
+	got := projectFields(utterances, []string{"text", "start_ms", "sentiment"})
+	want := []map[string]any{
+		{"text": "hi", "start_ms": int64(1000), "sentiment": positive},
+		{"text": "bye", "start_ms": int64(2500), "sentiment": (*Sentiment)(nil)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("projectFields = %v, want %v", got, want)
+	}
+}
+
+func TestHandleGetTranscriptionFields(t *testing.T) {
+	storeTestTranscription(t, "fields-1", []CleanUtterance{{Text: "hi", Speaker: "A", Sentiment: &Sentiment{Label: "NEUTRAL"}}})
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"format=json&fields=sentiment", http.StatusOK},
+		{"format=json&fields=text,speaker", http.StatusOK},
+		{"format=json&fields=volume", http.StatusBadRequest},
+		{"format=json&fields=,", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := serveTranscription(handleGetTranscription, "fields-1", tt.query); w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.query, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestWithPrecision(t *testing.T) {
+	utterances := []CleanUtterance{{
+		Text: "hi", Start: 1.23466, End: 2.5,
+		Words: []CleanWord{{Text: "hi", Start: 1.23466, End: 1.98765}},
+	}}
+
+	tests := []struct {
+		precision          int
+		start, end         float64
+		wordStart, wordEnd float64
+	}{
+		{0, 1, 3, 1, 2},
+		{1, 1.2, 2.5, 1.2, 2},
+		{3, 1.235, 2.5, 1.235, 1.988},
+	}
+	for _, tt := range tests {
+		got := withPrecision(utterances, tt.precision)[0]
+		if got.Start != tt.start || got.End != tt.end || got.Words[0].Start != tt.wordStart || got.Words[0].End != tt.wordEnd {
+			t.Errorf("precision %d: %v-%v, word %v-%v, want %v-%v, word %v-%v", tt.precision,
+				got.Start, got.End, got.Words[0].Start, got.Words[0].End, tt.start, tt.end, tt.wordStart, tt.wordEnd)
+		}
+	}
+	if utterances[0].Start != 1.23466 || utterances[0].Words[0].End != 1.98765 {
+		t.Error("withPrecision modified its input")
+	}
+}
+
+func TestHandleGetTranscriptionPrecision(t *testing.T) {
+	storeTestTranscription(t, "precision-1", []CleanUtterance{{Text: "hi", Start: 1.23466, End: 2.5}})
+
+	for _, p := range []string{"0", "3"} {
+		if w := serveTranscription(handleGetTranscription, "precision-1", "format=json&precision="+p); w.Code != http.StatusOK {
+			t.Errorf("precision=%s: status = %d, want 200", p, w.Code)
+		}
+	}
+	for _, p := range []string{"-1", "4", "two", "1.5"} {
+		if w := serveTranscription(handleGetTranscription, "precision-1", "format=json&precision="+p); w.Code != http.StatusBadRequest {
+			t.Errorf("precision=%s: status = %d, want 400", p, w.Code)
+		}
+	}
+}
+
+// texts returns the text of each utterance, in order.
+func texts(utterances []CleanUtterance) []string {
+	out := make([]string, len(utterances))
+	for i, u := range utterances {
+		out[i] = u.Text
+	}
+	return out
+}
+
+func TestSortByStart(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "c", Start: 3},
+		{Text: "a1", Start: 1},
+		{Text: "b", Start: 2},
+		{Text: "a2", Start: 1},
+	}
+
+	if got, want := texts(sortByStart(utterances, false)), []string{"a1", "a2", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("asc = %v, want %v", got, want)
+	}
+	// Equal starts keep their input order in both directions.
+	if got, want := texts(sortByStart(utterances, true)), []string{"c", "b", "a1", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("desc = %v, want %v", got, want)
+	}
+	if got, want := texts(utterances), []string{"c", "a1", "b", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("input reordered to %v", got)
+	}
+}
+
+func TestFilterByKeywords(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "Let's review the Budget"},
+		{Text: "The deadline moved"},
+		{Text: "Lunch?"},
+		{Text: "Budget and DEADLINE both slipped"},
+	}
+
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"budget", []string{"Let's review the Budget", "Budget and DEADLINE both slipped"}},
+		{"budget, deadline", []string{"Let's review the Budget", "The deadline moved", "Budget and DEADLINE both slipped"}},
+		{"DEAD", []string{"The deadline moved", "Budget and DEADLINE both slipped"}},
+		{"invoice,payroll", []string{}},
+	}
+	for _, tt := range tests {
+		kws, err := parseKeywords(tt.raw)
+		if err != nil {
+			t.Fatalf("parseKeywords(%q): %v", tt.raw, err)
+		}
+		if got := texts(filterByKeywords(utterances, kws)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+	if _, err := parseKeywords(" , "); err == nil {
+		t.Error("parseKeywords accepted a list without keywords")
+	}
+}
+
+func TestRebase(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Text: "b", Start: 12.5, End: 14, Words: []CleanWord{{Text: "b", Start: 12.5, End: 13}}},
+		{Text: "a", Start: 10, End: 12},
+		{Text: "c", Start: 15, End: 15.5},
+	}
+
+	got := rebase(utterances)
+	want := []CleanUtterance{
+		{Text: "b", Start: 2.5, End: 4, Words: []CleanWord{{Text: "b", Start: 2.5, End: 3}}},
+		{Text: "a", Start: 0, End: 2},
+		{Text: "c", Start: 5, End: 5.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rebase = %+v, want %+v", got, want)
+	}
+	if utterances[0].Start != 12.5 || utterances[0].Words[0].Start != 12.5 {
+		t.Error("rebase modified its input")
+	}
+	if got := rebase(want[1:2]); !reflect.DeepEqual(got, want[1:2]) {
+		t.Errorf("rebase of a transcript starting at 0 = %+v, want unchanged", got)
+	}
+	if got := rebase(nil); len(got) != 0 {
+		t.Errorf("rebase(nil) = %v, want empty", got)
+	}
+}
+
+func TestExtendUtterances(t *testing.T) {
+	useConfig(t, func(c *config) { c.ReadingWPM = 120 })
+	utterances := []CleanUtterance{{Text: "one two", Speaker: "A", Start: 1.2346, End: 2.25, Confidence: 0.9}}
+
+	tests := []struct {
+		milliseconds, readingTime bool
+		want                      string
+	}{
+		{false, false, `[{"text":"one two","speaker":"A","start":1.2346,"end":2.25,"confidence":0.9}]`},
+		{true, false, `[{"text":"one two","speaker":"A","start":1.2346,"end":2.25,"confidence":0.9,"start_ms":1235,"end_ms":2250}]`},
+		{false, true, `[{"text":"one two","speaker":"A","start":1.2346,"end":2.25,"confidence":0.9,"reading_time_ms":1000}]`},
+		{true, true, `[{"text":"one two","speaker":"A","start":1.2346,"end":2.25,"confidence":0.9,"start_ms":1235,"end_ms":2250,"reading_time_ms":1000}]`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(extendUtterances(utterances, tt.milliseconds, tt.readingTime))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("ms %v, reading time %v:\ngot  %s\nwant %s", tt.milliseconds, tt.readingTime, got, tt.want)
+		}
+	}
+}
+
+func TestHandleGetTranscriptionUnits(t *testing.T) {
+	storeTestTranscription(t, "units-1", []CleanUtterance{{Text: "hi", Start: 0.5, End: 1.25}})
+
+	tests := []struct {
+		query  string
+		wantMs bool
+	}{
+		{"format=json", false},
+		{"format=json&units=s", false},
+		{"format=json&units=both", true},
+		{"format=json&units=both&fields=text", true},
+	}
+	for _, tt := range tests {
+		w := serveTranscription(handleGetTranscription, "units-1", tt.query)
+		var got []map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil || len(got) != 1 {
+			t.Fatalf("%s: status %d, err %v, %d utterances", tt.query, w.Code, err, len(got))
+		}
+		_, hasStart := got[0]["start_ms"]
+		_, hasEnd := got[0]["end_ms"]
+		if hasStart != tt.wantMs || hasEnd != tt.wantMs {
+			t.Errorf("%s: got %v, want start_ms and end_ms %v", tt.query, got[0], tt.wantMs)
+		}
+		if tt.wantMs && (got[0]["start_ms"] != 500.0 || got[0]["end_ms"] != 1250.0) {
+			t.Errorf("%s: got %v, want start_ms 500 and end_ms 1250", tt.query, got[0])
+		}
+	}
+	if w := serveTranscription(handleGetTranscription, "units-1", "format=json&units=ms"); w.Code != http.StatusBadRequest {
+		t.Errorf("units=ms: status = %d, want 400", w.Code)
+	}
+}
+
+func TestReadingTimeMs(t *testing.T) {
+	tests := []struct {
+		text string
+		wpm  float64
+		want int64
+	}{
+		{"", 150, 0},
+		{" ... ", 150, 0},
+		{"hello", 150, 400},
+		{"one two three", 150, 1200},
+		{"don't stop, it's fine!", 120, 2000},
+		{"one two three", 7, 25714},
+		{"one two three", 0, 0},
+		{"one two three", -60, 0},
+	}
+	for _, tt := range tests {
+		if got := readingTimeMs(tt.text, tt.wpm); got != tt.want {
+			t.Errorf("readingTimeMs(%q, %v) = %d, want %d", tt.text, tt.wpm, got, tt.want)
+		}
+	}
+}
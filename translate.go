@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"golang.org/x/sync/singleflight"
+)
+
+// Translator is implemented by transcribers that can translate text with an LLM.
+type Translator interface {
+	// Translate returns texts translated into the target language, one translation per text, in order.
+	Translate(ctx context.Context, texts []string, target string) ([]string, error)
+}
+
+// newTranslator returns the Translator used for translations requested after upload.
+// It is a variable so translations can run against a fake LLM.
+var newTranslator = func(apiKey string) Translator {
+	return newAssemblyAITranscriber(apiKey, "")
+}
+
+// Translate asks LeMUR to translate texts into the target language in a single task.
+// The lines are sent and answered as JSON arrays, so they map back one to one.
+func (t *assemblyAITranscriber) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.LeMUR.Task(ctx, assemblyai.LeMURTaskParams{
+		Prompt: assemblyai.String(fmt.Sprintf(
+			"The input is a JSON array of lines of a meeting transcript. Translate every line into the language with the code %q. "+
+				"Answer with a JSON array of the translated lines only, in the same order, one per input line.", target)),
+		LeMURBaseParams: assemblyai.LeMURBaseParams{InputText: assemblyai.String(string(input))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseTranslatedLines(assemblyai.ToString(resp.Response), len(texts))
+}
+
+// parseTranslatedLines reads the JSON array of n lines answered by Translate,
+// ignoring any text around it, such as a code fence.
+func parseTranslatedLines(resp string, n int) ([]string, error) {
+	start, end := strings.Index(resp, "["), strings.LastIndex(resp, "]")
+	if start < 0 || end < start {
+		return nil, errors.New("translation is not a JSON array")
+	}
+	var lines []string
+	if err := json.Unmarshal([]byte(resp[start:end+1]), &lines); err != nil {
+		return nil, fmt.Errorf("unreadable translation: %w", err)
+	}
+	if len(lines) != n {
+		return nil, fmt.Errorf("translation has %d lines, want %d", len(lines), n)
+	}
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return lines, nil
+}
+
+// Limits of one translation batch: the utterances with text it holds, and their total length.
+const (
+	translationBatchSize  = 50
+	translationBatchChars = 8000
+)
+
+// translationBatches splits the indexes of the utterances with text into batches
+// within translationBatchSize and translationBatchChars. An utterance longer than
+// translationBatchChars gets a batch of its own.
+func translationBatches(utterances []CleanUtterance) [][]int {
+	var batches [][]int
+	var batch []int
+	chars := 0
+	for i, u := range utterances {
+		if strings.TrimSpace(u.Text) == "" {
+			continue
+		}
+		if len(batch) > 0 && (len(batch) == translationBatchSize || chars+len(u.Text) > translationBatchChars) {
+			batches = append(batches, batch)
+			batch, chars = nil, 0
+		}
+		batch = append(batch, i)
+		chars += len(u.Text)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// translationTargetPattern is the accepted format of a target language code, such as "en" or "pt-br".
+var translationTargetPattern = regexp.MustCompile(`^[a-z]{2,3}([_-][a-z]{2,4})?$`)
+
+// parseTranslationTarget validates an optional target language code, lowercasing it.
+// An empty code is allowed and means no translation.
+func parseTranslationTarget(v string) (string, error) {
+	v = strings.ToLower(v)
+	if v != "" && !translationTargetPattern.MatchString(v) {
+		return "", fmt.Errorf("%q is not a language code such as en or pt-br", v)
+	}
+	return v, nil
+}
+
+// translateUtterances translates the text of every utterance into target, one call
+// per batch of translationBatches, returning the translations in utterance order.
+// Utterances without text stay empty. It stops at the first failure, including ctx
+// ending, and returns the translations of the utterances before the failing batch,
+// a prefix of the result, with the error.
+func translateUtterances(ctx context.Context, t Translator, utterances []CleanUtterance, target string) ([]string, error) {
+	texts := make([]string, len(utterances))
+	done := 0
+	for _, batch := range translationBatches(utterances) {
+		in := make([]string, len(batch))
+		for j, i := range batch {
+			in[j] = utterances[i].Text
+		}
+		out, err := t.Translate(ctx, in, target)
+		if err == nil && len(out) != len(in) {
+			err = fmt.Errorf("translator returned %d lines for %d", len(out), len(in))
+		}
+		if err != nil {
+			return texts[:done], err
+		}
+		for j, i := range batch {
+			texts[i] = out[j]
+		}
+		done = batch[len(batch)-1] + 1
+	}
+	return texts, nil
+}
+
+// withTranslation returns a copy of utterances with Text replaced by texts and the
+// text it replaces kept in OriginalText. Timestamps and words are left as they are.
+func withTranslation(utterances []CleanUtterance, texts []string) []CleanUtterance {
+	out := make([]CleanUtterance, len(utterances))
+	for i, u := range utterances {
+		u.OriginalText = u.Text
+		u.Text = texts[i]
+		out[i] = u
+	}
+	return out
+}
+
+// translationFlights shares a translation in progress between the requests for the
+// same transcription and language, keyed by ID and language.
+var translationFlights singleflight.Group
+
+// translatedTranscript returns the utterances of entry translated into the ?lang of r.
+// A translation stored for that language is reused; otherwise the rest of the
+// utterances is translated, see resumeTranslation. Concurrent requests for the same
+// translation share one, which is not cancelled when a single requester goes away.
+// It writes an error and returns false for an invalid language, a missing API key,
+// or a failed translation.
+func translatedTranscript(w http.ResponseWriter, r *http.Request, id string, entry transcriptEntry) ([]CleanUtterance, bool) {
+	target, err := parseTranslationTarget(r.URL.Query().Get("lang"))
+	if err == nil && target == "" {
+		err = fmt.Errorf("must not be empty")
+	}
+	if err != nil {
+		http.Error(w, "lang: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if texts, ok := entry.Translations[target]; ok && len(texts) == len(entry.Utterances) {
+		return withTranslation(entry.Utterances, texts), true
+	}
+
+	apiKey := os.Getenv("ASSEMBLYAI_API_KEY")
+	if apiKey == "" {
+		loggerFrom(r.Context()).Error("API key not found in environment")
+		http.Error(w, "Transcription provider is not configured", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	v, err, _ := translationFlights.Do(id+"|"+target, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), cfg.TranslationTimeout)
+		defer cancel()
+		return resumeTranslation(ctx, newTranslator(apiKey), id, entry.Utterances, target)
+	})
+	if err != nil {
+		loggerFrom(r.Context()).Error("Translation failed", "conn_id", id, "lang", target, "error", err)
+		http.Error(w, "Translation failed: "+err.Error(), http.StatusBadGateway)
+		return nil, false
+	}
+	return withTranslation(entry.Utterances, v.([]string)), true
+}
+
+// resumeTranslation translates the utterances of transcription id into target
+// within ctx, starting after the translated prefix already stored, if any.
+// What it translates is stored even when it fails part way, so a retry
+// only translates, and is only billed for, the rest.
+func resumeTranslation(ctx context.Context, t Translator, id string, utterances []CleanUtterance, target string) ([]string, error) {
+	var done []string
+	if stored, ok, err := getTranscription(id); err == nil && ok && len(stored.Utterances) == len(utterances) {
+		done = stored.Translations[target]
+	}
+	if len(done) == len(utterances) {
+		return done, nil
+	}
+
+	rest, err := translateUtterances(ctx, t, utterances[len(done):], target)
+	texts := append(slices.Clip(done), rest...)
+	if len(rest) > 0 {
+		if _, serr := updateTranscription(id, func(e *transcriptEntry) error {
+			storeTranslation(e, target, texts)
+			return nil
+		}); serr != nil {
+			loggerFrom(ctx).Warn("Failed to cache translation", "conn_id", id, "lang", target, "error", serr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w (%d of %d utterances translated)", err, len(texts), len(utterances))
+	}
+	return texts, nil
+}
+
+// storeTranslation keeps the translated texts of entry for target. texts may be
+// a prefix of the utterances, left by a translation that failed part way.
+// The map is replaced rather than modified, since copies of the entry handed
+// out by getTranscription share it.
+func storeTranslation(entry *transcriptEntry, target string, texts []string) {
+	translations := maps.Clone(entry.Translations)
+	if translations == nil {
+		translations = make(map[string][]string)
+	}
+	translations[target] = texts
+	entry.Translations = translations
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTranslator translates a line by upper-casing it, counting its calls.
+// It fails from call failAt on, if set, and waits for gate, if set, before answering.
+type fakeTranslator struct {
+	failAt int
+	gate   chan struct{}
+
+	mu    sync.Mutex
+	calls int
+	lines int
+}
+
+func (f *fakeTranslator) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failAt > 0 && f.calls >= f.failAt {
+		return nil, errors.New("llm unavailable")
+	}
+	f.lines += len(texts)
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = strings.ToUpper(text)
+	}
+	return out, nil
+}
+
+func (f *fakeTranslator) counts() (calls, lines int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls, f.lines
+}
+
+func textUtterances(texts ...string) []CleanUtterance {
+	utterances := make([]CleanUtterance, len(texts))
+	for i, text := range texts {
+		utterances[i] = CleanUtterance{Text: text, Speaker: "A"}
+	}
+	return utterances
+}
+
+func TestParseTranslatedLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    string
+		n       int
+		want    []string
+		wantErr bool
+	}{
+		{"plain array", `["hola", " adiós "]`, 2, []string{"hola", "adiós"}, false},
+		{"code fence", "```json\n[\"hola\"]\n```", 1, []string{"hola"}, false},
+		{"wrong count", `["hola"]`, 2, nil, true},
+		{"not an array", "hola", 1, nil, true},
+		{"invalid json", `["hola",]`, 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTranslatedLines(tt.resp, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslationBatches(t *testing.T) {
+	long := strings.Repeat("x", translationBatchChars)
+	many := make([]string, translationBatchSize+1)
+	for i := range many {
+		many[i] = "line"
+	}
+
+	tests := []struct {
+		name  string
+		texts []string
+		want  []int
+	}{
+		{"empty", nil, nil},
+		{"skips blank utterances", []string{"a", " ", "b"}, []int{2}},
+		{"batch size", many, []int{translationBatchSize, 1}},
+		{"batch chars", []string{"a", long, "b"}, []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := translationBatches(textUtterances(tt.texts...))
+			var sizes []int
+			for _, b := range batches {
+				sizes = append(sizes, len(b))
+			}
+			if len(sizes) != len(tt.want) {
+				t.Fatalf("batch sizes = %v, want %v", sizes, tt.want)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.want[i] {
+					t.Errorf("batch sizes = %v, want %v", sizes, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateUtterancesKeepsPartialResult(t *testing.T) {
+	texts := make([]string, translationBatchSize+2)
+	for i := range texts {
+		texts[i] = "line"
+	}
+	texts[0] = ""
+	utterances := textUtterances(texts...)
+
+	got, err := translateUtterances(context.Background(), &fakeTranslator{failAt: 2}, utterances, "es")
+	if err == nil {
+		t.Fatal("want the failure of the second batch")
+	}
+	// The first batch covers the blank first utterance and translationBatchSize lines.
+	if len(got) != translationBatchSize+1 {
+		t.Fatalf("kept %d translations, want %d", len(got), translationBatchSize+1)
+	}
+	if got[0] != "" || got[1] != "LINE" {
+		t.Errorf("got %q..., want a blank then LINE", got[:2])
+	}
+}
+
+func TestTranslatedTranscriptResumesAndSharesWork(t *testing.T) {
+	useConfig(t, func(c *config) { c.TranslationTimeout = time.Minute })
+	t.Setenv("ASSEMBLYAI_API_KEY", "test-key")
+	texts := make([]string, translationBatchSize+1)
+	for i := range texts {
+		texts[i] = "line"
+	}
+	utterances := textUtterances(texts...)
+	saveTranscription("translate-1", transcriptEntry{Utterances: utterances})
+	t.Cleanup(func() { deleteTranscription("translate-1") })
+
+	useTranslator := func(f *fakeTranslator) {
+		restore := newTranslator
+		newTranslator = func(string) Translator { return f }
+		t.Cleanup(func() { newTranslator = restore })
+	}
+	request := func() (int, []CleanUtterance) {
+		entry, _, _ := getTranscription("translate-1")
+		w := httptest.NewRecorder()
+		out, _ := translatedTranscript(w, httptest.NewRequest("GET", "/?lang=es", nil), "translate-1", entry)
+		return w.Code, out
+	}
+
+	// The second batch fails; the first is kept.
+	failing := &fakeTranslator{failAt: 2}
+	useTranslator(failing)
+	if code, _ := request(); code != 502 {
+		t.Fatalf("status = %d, want 502", code)
+	}
+
+	// Concurrent retries share one translation of the remaining batch.
+	f := &fakeTranslator{gate: make(chan struct{})}
+	useTranslator(f)
+	var wg sync.WaitGroup
+	results := make([][]CleanUtterance, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = request()
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(f.gate)
+	wg.Wait()
+
+	calls, lines := f.counts()
+	if calls != 1 || lines != 1 {
+		t.Errorf("calls = %d, lines = %d, want one call for the one untranslated line", calls, lines)
+	}
+	for i, out := range results {
+		if len(out) != len(utterances) || out[len(out)-1].Text != "LINE" || out[0].OriginalText != "line" {
+			t.Errorf("request %d: got %d utterances, want the full translation", i, len(out))
+		}
+	}
+}
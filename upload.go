@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// errAudioTooLarge is returned by readAudio for uploads over the size limit.
+var errAudioTooLarge = errors.New("audio too large")
+
+// errNotBinary is returned by readAudio for a text message other than audioDoneMessage.
+var errNotBinary = errors.New("expected binary audio chunks followed by a done message")
+
+// errNoAudio is returned by readAudio when the upload ends before any audio arrived.
+var errNoAudio = errors.New("no audio received")
+
+// errAudioStorage is returned by readAudio when a large upload cannot be spilled to disk.
+var errAudioStorage = errors.New("audio storage failed")
+
+// audioDoneMessage is the text message that ends a chunked upload.
+const audioDoneMessage = "done"
+
+// readAudio reads binary audio chunks from conn into an uploadedAudio, until the
+// client sends the text message audioDoneMessage or closes the connection.
+// Past threshold bytes the audio goes to a temporary file, see uploadedAudio.
+// It stops reading as soon as the chunks pass limit bytes in total, so an oversized
+// upload is never stored, and leaves the connection open for an error frame.
+// conn.SetReadLimit is not used because it closes the connection before the
+// client can be told why. The caller must close the returned audio.
+func readAudio(conn *websocket.Conn, limit, threshold int) (*uploadedAudio, error) {
+	audio := &uploadedAudio{threshold: threshold}
+	for {
+		mt, r, err := conn.NextReader()
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && audio.Len() > 0 {
+			return audio, nil
+		}
+		if err != nil {
+			audio.close()
+			return nil, err
+		}
+		if mt == websocket.TextMessage {
+			msg, err := io.ReadAll(io.LimitReader(r, int64(len(audioDoneMessage))+1))
+			if err == nil && string(msg) != audioDoneMessage {
+				err = errNotBinary
+			}
+			if err == nil && audio.Len() == 0 {
+				err = errNoAudio
+			}
+			if err != nil {
+				audio.close()
+				return nil, err
+			}
+			return audio, nil
+		}
+
+		chunk, err := readChunk(r, audio.Len(), limit)
+		if err == nil {
+			err = audio.write(chunk)
+		}
+		if err != nil {
+			audio.close()
+			return nil, err
+		}
+	}
+}
+
+// readChunk reads one binary message from r, given that read bytes of audio came before it.
+// It reads at most one byte past limit in total, and fails with errAudioTooLarge
+// if the message takes the audio over limit.
+func readChunk(r io.Reader, read, limit int) ([]byte, error) {
+	chunk, err := io.ReadAll(io.LimitReader(r, int64(limit-read)+1))
+	if err != nil {
+		return nil, err
+	}
+	if read+len(chunk) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", errAudioTooLarge, limit)
+	}
+	return chunk, nil
+}
+
+// tempAudioPattern matches the temporary files uploads are spilled to, whatever their extension.
+// The prefix keeps the sweeper away from other programs' files.
+const tempAudioPattern = "meeting-audio-*"
+
+// audioHeaderBytes is how much of an upload uploadedAudio keeps in memory for
+// sniffing its format and WAV header, even once the rest is on disk.
+const audioHeaderBytes = 64 << 10
+
+// uploadedAudio is audio received from a client. Up to threshold bytes it is held
+// in memory; once it grows past that, it moves to a temporary file in TEMP_DIR,
+// named with the extension of its detected format, and later chunks are appended
+// to the file as they arrive, so a large upload is never held in memory whole.
+type uploadedAudio struct {
+	threshold int
+	size      int
+	// head is the first audioHeaderBytes of the audio.
+	head []byte
+	// data is the whole audio while it is in memory, and nil once it is in file.
+	data []byte
+	file *os.File
+}
+
+// write appends a chunk to the audio, spilling it to a temporary file when it passes the threshold.
+func (a *uploadedAudio) write(chunk []byte) error {
+	if n := min(len(chunk), audioHeaderBytes-len(a.head)); n > 0 {
+		a.head = append(a.head, chunk[:n]...)
+	}
+	a.size += len(chunk)
+	if a.file == nil && a.size <= a.threshold {
+		a.data = append(a.data, chunk...)
+		return nil
+	}
+
+	if a.file == nil {
+		ext := ""
+		if format, ok := detectAudioFormat(a.head); ok {
+			ext = format.Extension
+		}
+		f, err := os.CreateTemp(cfg.TempDir, tempAudioPattern+ext)
+		if err != nil {
+			return fmt.Errorf("%w: temp file creation failed: %v", errAudioStorage, err)
+		}
+		a.file = f
+		if _, err := f.Write(a.data); err != nil {
+			return fmt.Errorf("%w: failed to write to temp file: %v", errAudioStorage, err)
+		}
+		a.data = nil
+	}
+	if _, err := a.file.Write(chunk); err != nil {
+		return fmt.Errorf("%w: failed to write to temp file: %v", errAudioStorage, err)
+	}
+	return nil
+}
+
+// Len returns the number of bytes of audio received.
+func (a *uploadedAudio) Len() int {
+	return a.size
+}
+
+// header returns the first bytes of the audio, at most audioHeaderBytes of them.
+func (a *uploadedAudio) header() []byte {
+	return a.head
+}
+
+// open returns a reader over the whole audio for submission, from memory or the temporary file.
+// Every call returns an independent reader from the start, so a failed upload can be sent again.
+func (a *uploadedAudio) open() *audioBody {
+	var r io.Reader
+	if a.file != nil {
+		r = io.NewSectionReader(a.file, 0, int64(a.size))
+	} else {
+		r = bytes.NewReader(a.data)
+	}
+	return &audioBody{Reader: r, audio: a}
+}
+
+// close removes the temporary file, if any. The audio must not be opened afterwards.
+func (a *uploadedAudio) close() {
+	if a.file != nil {
+		a.file.Close()
+		os.Remove(a.file.Name())
+	}
+}
+
+// audioBody is a request body over uploaded audio. Being replayable, it lets the
+// provider transports retry the upload with a fresh copy, see withReplayableBody.
+type audioBody struct {
+	io.Reader
+	audio *uploadedAudio
+}
+
+// Close does nothing; the underlying audio is closed by its owner.
+func (b *audioBody) Close() error {
+	return nil
+}
+
+// reopen returns a fresh body over the same audio.
+func (b *audioBody) reopen() (io.ReadCloser, error) {
+	return b.audio.open(), nil
+}
+
+// length returns the size of the body in bytes.
+func (b *audioBody) length() int64 {
+	return int64(b.audio.Len())
+}
+
+// sweepTempFiles removes the temporary audio files in dir last modified more than maxAge before now.
+// Only files matching tempAudioPattern are considered. An empty dir means the system temp directory.
+// It returns the number of files removed.
+func sweepTempFiles(dir string, maxAge time.Duration, now time.Time) (int, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, tempAudioPattern))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			slog.Warn("Failed to remove stale temp file", "path", path, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// runTempSweeper sweeps stale temporary audio files left behind by a crash,
+// once at startup and then every interval, until ctx is cancelled.
+func runTempSweeper(ctx context.Context, dir string, maxAge, interval time.Duration, clock Clock) {
+	for {
+		n, err := sweepTempFiles(dir, maxAge, clock.Now())
+		if err != nil {
+			slog.Error("Failed to sweep temp files", "error", err)
+		} else if n > 0 {
+			slog.Info("Removed stale temp files", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+		}
+	}
+}
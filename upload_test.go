@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunk   string
+		read    int
+		limit   int
+		wantErr error
+	}{
+		{"within limit", "abcd", 0, 10, nil},
+		{"exactly at limit", "abcd", 6, 10, nil},
+		{"over limit", "abcd", 7, 10, errAudioTooLarge},
+	}
+	for _, tt := range tests {
+		got, err := readChunk(strings.NewReader(tt.chunk), tt.read, tt.limit)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr == nil && string(got) != tt.chunk {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.chunk)
+		}
+	}
+}
+
+func TestUploadedAudioSpillsPastThreshold(t *testing.T) {
+	useConfig(t, func(c *config) { c.TempDir = t.TempDir() })
+	wav := append([]byte("RIFF\x00\x00\x00\x00WAVE"), bytes.Repeat([]byte{1}, 100)...)
+
+	tests := []struct {
+		name      string
+		threshold int
+		wantFile  bool
+	}{
+		{"in memory", len(wav), false},
+		{"spilled", 50, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &uploadedAudio{threshold: tt.threshold}
+			for _, chunk := range [][]byte{wav[:30], wav[30:80], wav[80:]} {
+				if err := a.write(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if (a.file != nil) != tt.wantFile {
+				t.Fatalf("spilled = %v, want %v", a.file != nil, tt.wantFile)
+			}
+			if a.Len() != len(wav) || !bytes.Equal(a.header(), wav) {
+				t.Errorf("Len = %d, header %d bytes, want %d", a.Len(), len(a.header()), len(wav))
+			}
+
+			// Every open reads the whole audio from the start.
+			for i := 0; i < 2; i++ {
+				got, err := io.ReadAll(a.open())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, wav) {
+					t.Fatalf("open %d read %d bytes, want the audio", i, len(got))
+				}
+			}
+
+			a.close()
+			files, _ := filepath.Glob(filepath.Join(cfg.TempDir, tempAudioPattern))
+			if len(files) != 0 {
+				t.Errorf("temp files left after close: %v", files)
+			}
+			if tt.wantFile && !strings.HasSuffix(a.file.Name(), ".wav") {
+				t.Errorf("temp file %s not named by the detected format", a.file.Name())
+			}
+		})
+	}
+}
+
+func TestSpilledUploadIsRetried(t *testing.T) {
+	useConfig(t, func(c *config) {
+		c.TempDir = t.TempDir()
+		c.ProviderRetryAttempts, c.ProviderRetryBase = 2, time.Millisecond
+	})
+
+	a := &uploadedAudio{threshold: 4}
+	a.write([]byte("large audio body"))
+	defer a.close()
+
+	base := &scriptedTransport{statuses: []int{503, 200}}
+	transport := &retryTransport{base: base, clock: realClock{}}
+	// The provider SDK builds its upload request from a plain reader like this.
+	req, _ := http.NewRequest("POST", "https://provider.test/v2/upload", io.Reader(a.open()))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if sent := base.sent(); len(sent) != 2 || sent[0] != "large audio body" || sent[1] != sent[0] {
+		t.Errorf("sent %q, want the whole body on both attempts", sent)
+	}
+}
+
+func TestSweepTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := filepath.Join(dir, "meeting-audio-old.wav")
+	fresh := filepath.Join(dir, "meeting-audio-fresh.wav")
+	other := filepath.Join(dir, "other-old.wav")
+	for _, path := range []string{old, fresh, other} {
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Chtimes(old, now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+	os.Chtimes(other, now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+
+	n, err := sweepTempFiles(dir, time.Hour, now)
+	if err != nil || n != 1 {
+		t.Fatalf("sweepTempFiles = %d, %v, want 1 removed", n, err)
+	}
+	for path, want := range map[string]bool{old: false, fresh: true, other: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(path), err == nil, want)
+		}
+	}
+}
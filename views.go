@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preview is a lightweight summary of a transcript for list UIs.
+// First and Last are nil when the transcript has no utterances.
+type Preview struct {
+	Count int             `json:"count"`
+	First *CleanUtterance `json:"first"`
+	Last  *CleanUtterance `json:"last"`
+}
+
+// buildPreview returns the first and last utterance of a transcript and its size.
+// Word timings are left out to keep the preview small.
+func buildPreview(utterances []CleanUtterance) Preview {
+	p := Preview{Count: len(utterances)}
+	if len(utterances) == 0 {
+		return p
+	}
+
+	first := utterances[0]
+	first.Words = nil
+	last := utterances[len(utterances)-1]
+	last.Words = nil
+	p.First = &first
+	p.Last = &last
+	return p
+}
+
+// handleGetPreview returns the preview of a transcription.
+// If the transcription is not found, it returns a 404 error.
+func handleGetPreview(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPreview(data))
+}
+
+// importanceScore scores how much an utterance contributes to a transcript.
+// Longer utterances carry more content; the score is its word count.
+func importanceScore(u CleanUtterance) float64 {
+	return float64(len(strings.Fields(u.Text)))
+}
+
+// abridge returns the highest-scoring fraction of utterances, kept in their original order.
+// At least one utterance is returned for a non-empty transcript. Ties keep the earlier utterance.
+func abridge(utterances []CleanUtterance, ratio float64) []CleanUtterance {
+	n := int(math.Ceil(float64(len(utterances)) * ratio))
+	if n > len(utterances) {
+		n = len(utterances)
+	}
+
+	idx := make([]int, len(utterances))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		return importanceScore(utterances[idx[a]]) > importanceScore(utterances[idx[b]])
+	})
+	idx = idx[:n]
+	sort.Ints(idx)
+
+	out := make([]CleanUtterance, n)
+	for i, j := range idx {
+		out[i] = utterances[j]
+	}
+	return out
+}
+
+// handleGetAbridged returns the most important ?ratio of a transcription's utterances (default 0.2).
+// If the transcription is not found, it returns a 404 error.
+func handleGetAbridged(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	ratio := 0.2
+	if v := r.URL.Query().Get("ratio"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			http.Error(w, "ratio must be a number in (0, 1]", http.StatusBadRequest)
+			return
+		}
+		ratio = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(abridge(data, ratio))
+}
+
+// noSpeakerLabel is the lane label used when utterances carry no speaker.
+const noSpeakerLabel = "unknown"
+
+// TimelineSegment is a span of time in which a speaker is talking.
+type TimelineSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// TimelineLane holds the talking segments of a single speaker.
+type TimelineLane struct {
+	Label    string            `json:"label"`
+	Segments []TimelineSegment `json:"segments"`
+}
+
+// Timeline is a compact per-speaker view of a transcript for visualization.
+type Timeline struct {
+	Speakers []TimelineLane `json:"speakers"`
+}
+
+// buildTimeline groups utterance time spans into one lane per speaker,
+// ordered by first appearance. Utterances without a speaker label share
+// a noSpeakerLabel lane, so an unlabeled transcript has a single lane.
+func buildTimeline(utterances []CleanUtterance) Timeline {
+	t := Timeline{Speakers: []TimelineLane{}}
+	lanes := make(map[string]int)
+	for _, u := range utterances {
+		label := u.Speaker
+		if label == "" {
+			label = noSpeakerLabel
+		}
+		i, ok := lanes[label]
+		if !ok {
+			i = len(t.Speakers)
+			lanes[label] = i
+			t.Speakers = append(t.Speakers, TimelineLane{Label: label})
+		}
+		t.Speakers[i].Segments = append(t.Speakers[i].Segments, TimelineSegment{Start: u.Start, End: u.End})
+	}
+	return t
+}
+
+// handleGetTimeline returns the per-speaker timeline of a transcription.
+// If the transcription is not found, it returns a 404 error.
+func handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildTimeline(data))
+}
+
+// KaraokeWord is one word of a karaoke line with its timing.
+type KaraokeWord struct {
+	Text  string  `json:"text"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// KaraokeLine is one utterance shown as a line of karaoke text.
+type KaraokeLine struct {
+	Speaker string        `json:"speaker"`
+	Start   float64       `json:"start"`
+	End     float64       `json:"end"`
+	Words   []KaraokeWord `json:"words"`
+}
+
+// buildKaraoke groups the word timings into one line per utterance, in transcript order.
+func buildKaraoke(utterances []CleanUtterance) []KaraokeLine {
+	lines := make([]KaraokeLine, len(utterances))
+	for i, u := range utterances {
+		words := make([]KaraokeWord, len(u.Words))
+		for j, w := range u.Words {
+			words[j] = KaraokeWord{Text: w.Text, Start: w.Start, End: w.End}
+		}
+		lines[i] = KaraokeLine{Speaker: u.Speaker, Start: u.Start, End: u.End, Words: words}
+	}
+	return lines
+}
+
+// handleGetKaraoke returns the transcription as karaoke lines with per-word timing.
+// It returns 422 when the transcription has no word timestamps, and a 404 error
+// if the transcription is not found.
+func handleGetKaraoke(w http.ResponseWriter, r *http.Request) {
+	data, ok := loadTranscription(w, r)
+	if !ok {
+		return
+	}
+	if !hasWordTimestamps(data) {
+		http.Error(w, "Word timestamps not available", http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildKaraoke(data))
+}
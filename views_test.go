@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBuildPreview(t *testing.T) {
+	words := []CleanWord{{Text: "w", Start: 0, End: 1}}
+	utterances := []CleanUtterance{
+		{Text: "first", Speaker: "A", Start: 0, End: 1, Words: words},
+		{Text: "middle", Speaker: "B", Start: 1, End: 2, Words: words},
+		{Text: "last", Speaker: "A", Start: 2, End: 3, Words: words},
+	}
+
+	p := buildPreview(utterances)
+	if p.Count != 3 {
+		t.Errorf("count = %d, want 3", p.Count)
+	}
+	if p.First == nil || p.First.Text != "first" || p.Last == nil || p.Last.Text != "last" {
+		t.Fatalf("preview = %+v, want the first and last utterance", p)
+	}
+	if p.First.Words != nil || p.Last.Words != nil {
+		t.Error("preview kept the word timings")
+	}
+	if utterances[0].Words == nil {
+		t.Error("buildPreview modified its input")
+	}
+
+	single := buildPreview(utterances[:1])
+	if single.First == nil || single.Last == nil || single.First.Text != "first" || single.Last.Text != "first" {
+		t.Errorf("single preview = %+v, want the same utterance first and last", single)
+	}
+}
+
+func TestBuildPreviewEmpty(t *testing.T) {
+	if got, want := buildPreview(nil), (Preview{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildPreview(nil) = %+v, want %+v", got, want)
+	}
+}
+
+func TestAbridge(t *testing.T) {
+	// Scores: 2, 4, 1, 4, 3 words.
+	utterances := []CleanUtterance{
+		{Text: "two words"},
+		{Text: "this one has four"},
+		{Text: "one"},
+		{Text: "so does this one"},
+		{Text: "three words here"},
+	}
+
+	tests := []struct {
+		ratio float64
+		want  []string
+	}{
+		{0.01, []string{"this one has four"}},
+		{0.2, []string{"this one has four"}},
+		{0.21, []string{"this one has four", "so does this one"}},
+		{0.4, []string{"this one has four", "so does this one"}},
+		{0.6, []string{"this one has four", "so does this one", "three words here"}},
+		{1, texts(utterances)},
+	}
+	for _, tt := range tests {
+		if got := texts(abridge(utterances, tt.ratio)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ratio %v: got %v, want %v", tt.ratio, got, tt.want)
+		}
+	}
+	if got := abridge(nil, 0.5); len(got) != 0 {
+		t.Errorf("abridge(nil) = %v, want empty", got)
+	}
+}
+
+func TestHandleGetAbridgedRatio(t *testing.T) {
+	storeTestTranscription(t, "abridged-1", []CleanUtterance{{Text: "hi"}})
+
+	for _, v := range []string{"", "ratio=1", "ratio=0.001"} {
+		if w := serveTranscription(handleGetAbridged, "abridged-1", v); w.Code != http.StatusOK {
+			t.Errorf("%q: status = %d, want 200", v, w.Code)
+		}
+	}
+	for _, v := range []string{"ratio=0", "ratio=1.01", "ratio=-0.5", "ratio=half"} {
+		if w := serveTranscription(handleGetAbridged, "abridged-1", v); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", v, w.Code)
+		}
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "B", Start: 0, End: 2},
+		{Speaker: "A", Start: 2, End: 3},
+		{Speaker: "B", Start: 5, End: 6},
+		{Start: 7, End: 8},
+		{Speaker: "A", Start: 10, End: 12},
+	}
+
+	got := buildTimeline(utterances)
+	want := Timeline{Speakers: []TimelineLane{
+		{Label: "B", Segments: []TimelineSegment{{Start: 0, End: 2}, {Start: 5, End: 6}}},
+		{Label: "A", Segments: []TimelineSegment{{Start: 2, End: 3}, {Start: 10, End: 12}}},
+		{Label: noSpeakerLabel, Segments: []TimelineSegment{{Start: 7, End: 8}}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildTimeline = %+v, want %+v", got, want)
+	}
+	if got := buildTimeline(nil); got.Speakers == nil || len(got.Speakers) != 0 {
+		t.Errorf("buildTimeline(nil).Speakers = %#v, want empty", got.Speakers)
+	}
+}
+
+func TestBuildKaraoke(t *testing.T) {
+	utterances := []CleanUtterance{
+		{Speaker: "A", Text: "Sing along", Start: 1, End: 2, Confidence: 0.9, Words: []CleanWord{
+			{Text: "Sing", Start: 1, End: 1.4, Confidence: 0.9},
+			{Text: "along", Start: 1.5, End: 2, Confidence: 0.8},
+		}},
+		{Speaker: "B", Text: "(applause)", Start: 2.5, End: 3},
+	}
+
+	got := buildKaraoke(utterances)
+	want := []KaraokeLine{
+		{Speaker: "A", Start: 1, End: 2, Words: []KaraokeWord{{Text: "Sing", Start: 1, End: 1.4}, {Text: "along", Start: 1.5, End: 2}}},
+		{Speaker: "B", Start: 2.5, End: 3, Words: []KaraokeWord{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildKaraoke = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandleGetKaraokeRequiresWordTimings(t *testing.T) {
+	storeTestTranscription(t, "karaoke-1", []CleanUtterance{{Text: "hi", Words: []CleanWord{{Text: "hi", End: 1}}}})
+	storeTestTranscription(t, "karaoke-none", []CleanUtterance{{Text: "hi"}})
+
+	if w := serveTranscription(handleGetKaraoke, "karaoke-1", ""); w.Code != http.StatusOK {
+		t.Errorf("with words: status = %d, want 200", w.Code)
+	}
+	if w := serveTranscription(handleGetKaraoke, "karaoke-none", ""); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("without words: status = %d, want 422", w.Code)
+	}
+}
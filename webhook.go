@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// webhookSignatureHeader carries the HMAC-SHA256 of the webhook body, as "sha256=<hex>".
+const webhookSignatureHeader = "X-Webhook-Signature"
+
+// webhookBaseDelay is the first wait between webhook attempts, doubling each time.
+const webhookBaseDelay = time.Second
+
+// webhookHTTPClient is the HTTP client used to deliver webhooks.
+var webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Statuses of a webhookPayload.
+const (
+	webhookCompleted = "completed"
+	webhookFailed    = "failed"
+)
+
+// webhookPayload is the JSON posted to a callback_url once a transcription is done.
+// Utterances are set for completed transcriptions and Error for failed ones.
+type webhookPayload struct {
+	ConnectionID string           `json:"connection_id"`
+	Status       string           `json:"status"`
+	Language     string           `json:"language,omitempty"`
+	CostCenter   string           `json:"cost_center,omitempty"`
+	Utterances   []CleanUtterance `json:"utterances,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// signWebhook returns the webhookSignatureHeader value of body for secret.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook posts body to url, making up to attempts attempts in total while
+// the request fails or the receiver answers with a non-2xx status.
+// The waits grow exponentially from webhookBaseDelay with random jitter.
+// With a secret, the body is signed in webhookSignatureHeader.
+func deliverWebhook(ctx context.Context, clock Clock, url string, body []byte, secret string, attempts int) error {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		lastErr = postWebhook(ctx, url, body, secret)
+		if lastErr == nil || attempt >= attempts {
+			return lastErr
+		}
+
+		wait := backoffDelay(webhookBaseDelay, attempt)
+		loggerFrom(ctx).Warn("Webhook delivery failed, retrying", "attempt", attempt, "error", lastErr, "wait", wait.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// postWebhook makes a single webhook delivery attempt.
+func postWebhook(ctx context.Context, url string, body []byte, secret string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhook(secret, body))
+	}
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyWebhook delivers payload to url in the background, so a slow or failing
+// receiver never holds up the transcription. The outcome is only logged.
+func notifyWebhook(logger *slog.Logger, url string, payload webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("Failed to encode webhook payload", "error", err)
+		return
+	}
+
+	backgroundJobs.Go(func() {
+		ctx := withLogger(context.Background(), logger)
+		if err := deliverWebhook(ctx, realClock{}, url, body, cfg.WebhookSecret, cfg.WebhookAttempts); err != nil {
+			logger.Error("Webhook delivery failed", "status", payload.Status, "error", err)
+			return
+		}
+		logger.Info("Webhook delivered", "status", payload.Status)
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+// workerPool bounds the number of transcriptions running in the background.
+type workerPool struct {
+	slots chan struct{}
+}
+
+// newWorkerPool creates a workerPool running at most size jobs at once.
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{slots: make(chan struct{}, size)}
+}
+
+// tryAcquire takes a slot if one is free, returning the function that gives it back.
+// It never waits, so a full pool turns work away instead of queueing it.
+// A nil pool has no limit.
+func (p *workerPool) tryAcquire() (release func(), ok bool) {
+	if p == nil {
+		return func() {}, true
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return func() { <-p.slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// transcriptionWorkers bounds the background transcriptions of /transcribe and
+// asynchronous uploads, or is nil when TRANSCRIPTION_WORKERS is 0. It is set up in main.
+var transcriptionWorkers *workerPool
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/AssemblyAI/assemblyai-go-sdk"
+	"github.com/gorilla/websocket"
+)
+
+// Error codes sent to WebSocket clients, one per failing stage of handleWS.
+const (
+	errCodeInvalidAudio        = "invalid_audio"
+	errCodeAudioTooLarge       = "audio_too_large"
+	errCodeUnsupportedFormat   = "unsupported_format"
+	errCodeAudioStorage        = "audio_storage_failed"
+	errCodeNotConfigured       = "not_configured"
+	errCodeSubmit              = "submit_failed"
+	errCodePoll                = "polling_failed"
+	errCodeProviderError       = "provider_error"
+	errCodeProviderUnavailable = "provider_unavailable"
+	errCodeCancelled           = "cancelled"
+	errCodeTimeout             = "timeout"
+	errCodeFetch               = "fetch_failed"
+	errCodeStream              = "stream_failed"
+	errCodeRateLimited         = "rate_limited"
+	errCodeBusy                = "busy"
+	errCodeNoSpeech            = "no_speech"
+)
+
+// retryableErrCodes lists the codes for which resending the same audio may succeed.
+var retryableErrCodes = map[string]bool{
+	errCodeAudioStorage:        true,
+	errCodeSubmit:              true,
+	errCodePoll:                true,
+	errCodeProviderUnavailable: true,
+	errCodeTimeout:             true,
+	errCodeFetch:               true,
+	errCodeStream:              true,
+	errCodeRateLimited:         true,
+	errCodeBusy:                true,
+}
+
+// wsError is the frame sent to the client when a transcription fails.
+type wsError struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	Detail    string `json:"detail"`
+	Retryable bool   `json:"retryable"`
+}
+
+// sendWSError writes an error frame to the client, such as
+// {"error": "transcription failed", "code": "submit_failed", "detail": "...", "retryable": true}.
+// Write failures are only logged, since the socket is about to be closed anyway.
+func sendWSError(conn *websocket.Conn, code, detail string) {
+	frame := wsError{Error: "transcription failed", Code: code, Detail: detail, Retryable: retryableErrCodes[code]}
+	if err := conn.WriteJSON(frame); err != nil {
+		slog.Warn("Failed to send error frame", "code", code, "error", err)
+	}
+}
+
+// pollErrorCode picks the error code for a failure while waiting on a transcription.
+// Cancellation, timeouts, and errors reported by the provider for the transcript
+// itself are told apart from failures to reach the provider, and a provider
+// answering with something other than JSON, such as an outage page, from both.
+func pollErrorCode(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return errCodeCancelled
+	case errors.Is(err, ErrTranscriptionTimeout):
+		return errCodeTimeout
+	case errors.Is(err, ErrTranscriptFailed):
+		return errCodeProviderError
+	case errors.Is(err, ErrProviderUnavailable):
+		return errCodeProviderUnavailable
+	default:
+		return errCodePoll
+	}
+}
+
+// statusWriteTimeout bounds a status frame write, so a slow client cannot hold up its handler.
+const statusWriteTimeout = 5 * time.Second
+
+// sendWSStatus writes a progress frame such as {"status": "processing"} to the client.
+// Write failures are only logged.
+func sendWSStatus(conn *websocket.Conn, status assemblyai.TranscriptStatus) {
+	conn.SetWriteDeadline(time.Now().Add(statusWriteTimeout))
+	defer conn.SetWriteDeadline(time.Time{})
+	if err := conn.WriteJSON(map[string]string{"status": string(status)}); err != nil {
+		slog.Warn("Failed to send status frame", "status", status, "error", err)
+	}
+}
+
+// statusQueueSize is how many status changes a statusWriter holds before dropping them.
+const statusQueueSize = 8
+
+// statusWriter sends progress frames to a WebSocket client from a goroutine owned
+// by its handler, so the shared poller never writes to the connection itself:
+// it only queues statuses with send, which never blocks.
+type statusWriter struct {
+	statuses chan assemblyai.TranscriptStatus
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// startStatusWriter starts writing the statuses queued with send to conn.
+// The caller must call close before writing to conn itself.
+func startStatusWriter(conn *websocket.Conn) *statusWriter {
+	s := &statusWriter{
+		statuses: make(chan assemblyai.TranscriptStatus, statusQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		for {
+			select {
+			case status := <-s.statuses:
+				sendWSStatus(conn, status)
+			case <-s.stop:
+				// Flush what was queued before close, such as the final "completed".
+				for {
+					select {
+					case status := <-s.statuses:
+						sendWSStatus(conn, status)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return s
+}
+
+// send queues a status frame. It never blocks: when the queue is full, or the
+// writer was closed, the status is dropped.
+func (s *statusWriter) send(status assemblyai.TranscriptStatus) {
+	select {
+	case s.statuses <- status:
+	default:
+	}
+}
+
+// close stops the writer once the frames queued so far are written,
+// after which the caller is the only writer of the connection.
+func (s *statusWriter) close() {
+	close(s.stop)
+	<-s.done
+}